@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -13,70 +14,98 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-// getItems fetches multiple items (events or locations) from database
+// getItems fetches a page of items (events or locations) from database
 func getItems(c *gin.Context) {
-	var size int = 10
+	query, err := parseItemQuery(c)
+	if err != nil {
+		log.Error(err)
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	}
 
-	sizeStr := c.Query("size")
-	if sizeStr != "" {
-		var err error
+	types, ok := scopeItemTypes(c, query.Type)
+	if !ok {
+		return
+	}
+	query.Types = types
 
-		size, err = strconv.Atoi(sizeStr)
+	page, err := itemStore.List(query)
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch items",
+		})
+		return
+	}
+
+	decodedItems := make([]DecodedItem, 0, len(page.Items))
+
+	for _, item := range page.Items {
+		decodedItem, err := item.Decode()
 		if err != nil {
 			log.Error(err)
-			c.JSON(400, gin.H{
+			c.JSON(500, gin.H{
 				"status":  "error",
-				"message": "size is not a valid number",
+				"message": "could not decode an item",
 			})
 			return
 		}
+
+		decodedItems = append(decodedItems, decodedItem)
 	}
 
-	if size < 0 {
-		size = 1
+	var nextCursor string
+	if page.NextCursor != nil {
+		nextCursor = page.NextCursor.Encode()
 	}
 
-	db, err := dbConn()
+	c.JSON(200, gin.H{
+		"items":      decodedItems,
+		"nextCursor": nextCursor,
+		"total":      page.Total,
+	})
+}
+
+// getNearbyItems lists the items within "radius" meters of "lat" and "lng", closest first,
+// with the distance to each of them in meters
+func getNearbyItems(c *gin.Context) {
+	query, err := parseNearbyQuery(c)
 	if err != nil {
-		log.Error(err)
-		c.JSON(500, gin.H{
+		c.JSON(400, gin.H{
 			"status":  "error",
-			"message": "could not connect to the database",
+			"message": err.Error(),
 		})
 		return
 	}
-	defer db.Close()
 
-	rows, err := db.Query("SELECT id, data, created_at, updated_at FROM items LIMIT $1", size)
+	types, ok := scopeItemTypes(c, query.Type)
+	if !ok {
+		return
+	}
+	query.Types = types
+
+	items, err := itemStore.Nearby(query)
 	if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not execute query",
+			"message": "could not fetch items",
 		})
 		return
 	}
-	defer rows.Close()
 
-	items := make([]DecodedItem, 0)
+	type nearbyItem struct {
+		DecodedItem
+		Distance float64 `json:"distance"`
+	}
 
-	for rows.Next() {
-		var item Item
-
-		if err = rows.Scan(
-			&item.ID,
-			&item.Data,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		); err != nil {
-			log.Error(err)
-			c.JSON(500, gin.H{
-				"status":  "error",
-				"message": "could not fetch an item",
-			})
-			return
-		}
+	nearbyItems := make([]nearbyItem, 0, len(items))
 
+	for _, item := range items {
 		decodedItem, err := item.Decode()
 		if err != nil {
 			log.Error(err)
@@ -87,10 +116,12 @@ func getItems(c *gin.Context) {
 			return
 		}
 
-		items = append(items, decodedItem)
+		nearbyItems = append(nearbyItems, nearbyItem{decodedItem, item.Distance})
 	}
 
-	c.JSON(200, items)
+	c.JSON(200, gin.H{
+		"items": nearbyItems,
+	})
 }
 
 // getItem fetches a single item (event or location) from database
@@ -105,25 +136,14 @@ func getItem(c *gin.Context) {
 		return
 	}
 
-	db, err := dbConn()
-	if err != nil {
-		log.Error(err)
-		c.JSON(500, gin.H{
+	item, err := itemStore.Get(int64(id))
+	if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
 			"status":  "error",
-			"message": "could not connect to the database",
+			"message": "item not found",
 		})
 		return
-	}
-	defer db.Close()
-
-	var item Item
-
-	if err := db.QueryRow("SELECT id, data, created_at, updated_at FROM items WHERE id = $1", id).Scan(
-		&item.ID,
-		&item.Data,
-		&item.CreatedAt,
-		&item.UpdatedAt,
-	); err != nil {
+	} else if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
@@ -143,6 +163,12 @@ func getItem(c *gin.Context) {
 		return
 	}
 
+	if !checkItemType(c, itemCommonData.Type) {
+		return
+	}
+
+	c.Header("ETag", item.ETag())
+
 	switch itemCommonData.Type {
 	case "location":
 		var location Location
@@ -186,6 +212,29 @@ func getItem(c *gin.Context) {
 	}
 }
 
+// checkImagesStored reports whether the images that data refers to by ID are stored.
+// It responds with 422 and returns false when some are unknown.
+func checkImagesStored(c *gin.Context, data map[string]interface{}) bool {
+	err := checkStoredImages(data, blobStore)
+	if verr, ok := err.(*ValidationError); ok {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": "item data is not valid",
+			"errors":  verr.Errors,
+		})
+		return false
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not look up images",
+		})
+		return false
+	}
+
+	return true
+}
+
 // postItem creates a new item (event or location) in database
 func postItem(c *gin.Context) {
 	var data map[string]interface{}
@@ -198,23 +247,39 @@ func postItem(c *gin.Context) {
 		return
 	}
 
-	db, err := dbConn()
-	if err != nil {
-		log.Error(err)
-		c.JSON(500, gin.H{
+	stripReadOnlyFields(data)
+
+	if err := validateItemData(data); err != nil {
+		c.JSON(422, gin.H{
 			"status":  "error",
-			"message": "could not connect to the database",
+			"message": "item data is not valid",
+			"errors":  err.(*ValidationError).Errors,
 		})
 		return
 	}
-	defer db.Close()
+
+	if !checkItemType(c, data["type"].(string)) {
+		return
+	}
+
+	expandOSMOpeningHours(data)
+
+	if !checkImagesStored(c, data) {
+		return
+	}
 
 	// Check for images and store them as files
-	if err = storeImages(data); err != nil {
+	if err := storeImages(data); err == errImageType || err == errImageDimensions {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	} else if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not store images to the filesystem",
+			"message": "could not store images",
 		})
 		return
 	}
@@ -229,7 +294,8 @@ func postItem(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Exec("INSERT INTO items (data, created_at, updated_at) VALUES ($1, NOW(), NOW())", dataBytes); err != nil {
+	item, err := itemStore.Create(dataBytes)
+	if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
@@ -238,9 +304,12 @@ func postItem(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
+	c.Header("ETag", item.ETag())
+	c.JSON(201, gin.H{
 		"status":  "ok",
 		"message": "successfully created a new item",
+		"id":      item.ID,
+		"version": item.Version,
 	})
 }
 
@@ -266,23 +335,64 @@ func putItem(c *gin.Context) {
 		return
 	}
 
-	db, err := dbConn()
-	if err != nil {
+	item, err := itemStore.Get(int64(id))
+	if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+		return
+	} else if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not connect to the database",
+			"message": "could not fetch an item",
 		})
 		return
 	}
-	defer db.Close()
+
+	if !checkItemType(c, itemType(item.Data)) {
+		return
+	}
+
+	version, ok := checkIfMatch(c, item)
+	if !ok {
+		return
+	}
+
+	stripReadOnlyFields(data)
+
+	if err := validateItemData(data); err != nil {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": "item data is not valid",
+			"errors":  err.(*ValidationError).Errors,
+		})
+		return
+	}
+
+	if !checkItemType(c, data["type"].(string)) {
+		return
+	}
+
+	expandOSMOpeningHours(data)
+
+	if !checkImagesStored(c, data) {
+		return
+	}
 
 	// Check for images and store them as files
-	if err = storeImages(data); err != nil {
+	if err := storeImages(data); err == errImageType || err == errImageDimensions {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	} else if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not store images to the filesystem",
+			"message": "could not store images",
 		})
 		return
 	}
@@ -297,7 +407,17 @@ func putItem(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Exec("UPDATE items SET data = $1, updated_at = NOW() WHERE id = $2", dataBytes, id); err != nil {
+	item, err = itemStore.Update(item.ID, dataBytes, version)
+	if err == ErrVersionMismatch {
+		respondVersionMismatch(c, item.ID)
+		return
+	} else if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+		return
+	} else if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
@@ -306,93 +426,294 @@ func putItem(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", item.ETag())
 	c.JSON(200, gin.H{
 		"status":  "ok",
 		"message": "successfully updated item",
+		"version": item.Version,
 	})
 }
 
-// deleteItem deletes an item (event or location) in the database
-func deleteItem(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+// patchItem applies a JSON merge patch (RFC 7386) or a JSON patch (RFC 6902)
+// to an existing item (event or location) in the database
+func patchItem(c *gin.Context) {
+	id, ok := itemIDParam(c)
+	if !ok {
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != "application/merge-patch+json" && contentType != "application/json-patch+json" {
+		c.JSON(415, gin.H{
+			"status":  "error",
+			"message": "content type must be application/merge-patch+json or application/json-patch+json",
+		})
+		return
+	}
+
+	patch, err := c.GetRawData()
 	if err != nil {
 		log.Error(err)
 		c.JSON(400, gin.H{
 			"status":  "error",
-			"message": errors.New("ID is not valid"),
+			"message": "could not read the request",
 		})
 		return
 	}
 
-	db, err := dbConn()
-	if err != nil {
+	item, err := itemStore.Get(id)
+	if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+		return
+	} else if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not connect to the database",
+			"message": "could not fetch an item",
 		})
 		return
 	}
-	defer db.Close()
 
-	if _, err := db.Exec("DELETE FROM items WHERE id = $1", id); err != nil {
+	if !checkItemType(c, itemType(item.Data)) {
+		return
+	}
+
+	version, ok := checkIfMatch(c, item)
+	if !ok {
+		return
+	}
+
+	var original, patched map[string]interface{}
+
+	// Decode the stored data twice, since patching may modify the document in place
+	if err = json.Unmarshal(item.Data, &original); err == nil {
+		err = json.Unmarshal(item.Data, &patched)
+	}
+	if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not delete item from the database",
+			"message": "could not decode an item",
 		})
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"status":  "ok",
-		"message": "successfully deleted item",
-	})
-}
+	var result interface{}
 
-// postGenerate generates markdown pages and places assets in the Zola directory
-func postGenerate(c *gin.Context) {
-	typ := c.Param("typ")
+	if contentType == "application/merge-patch+json" {
+		var mergePatchData interface{}
 
-	db, err := dbConn()
+		if err := json.Unmarshal(patch, &mergePatchData); err != nil {
+			c.JSON(400, gin.H{
+				"status":  "error",
+				"message": "could not parse JSON in the request",
+			})
+			return
+		}
+
+		result = mergePatch(patched, mergePatchData)
+	} else if result, err = applyJSONPatch(patched, patch); err != nil {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": "could not apply JSON patch: " + err.Error(),
+		})
+		return
+	}
+
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": "patched item data must be a JSON object",
+		})
+		return
+	}
+
+	stripReadOnlyFields(data)
+
+	if err := validateItemData(data); err != nil {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": "item data is not valid",
+			"errors":  err.(*ValidationError).Errors,
+		})
+		return
+	}
+
+	if !checkItemType(c, data["type"].(string)) {
+		return
+	}
+
+	expandOSMOpeningHours(data)
+
+	// Only look for new images in the image fields that were changed by the patch
+	changedImages := make(map[string]interface{})
+	for _, k := range []string{"coverImageURL", "imageURLs"} {
+		if v, ok := data[k]; ok && !reflect.DeepEqual(v, original[k]) {
+			changedImages[k] = v
+		}
+	}
+
+	if !checkImagesStored(c, changedImages) {
+		return
+	}
+
+	if err := storeImages(changedImages); err == errImageType || err == errImageDimensions {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not store images",
+		})
+		return
+	}
+
+	for k, v := range changedImages {
+		data[k] = v
+	}
+
+	dataBytes, err := json.Marshal(data)
 	if err != nil {
 		log.Error(err)
 		c.JSON(500, gin.H{
 			"status":  "error",
-			"message": "could not connect to the database",
+			"message": "could not marshal JSON value",
 		})
 		return
 	}
-	defer db.Close()
 
-	rows, err := db.Query("SELECT id, data, created_at, updated_at FROM items WHERE data->>'type' = $1", typ)
+	item, err = itemStore.Update(item.ID, dataBytes, version)
+	if err == ErrVersionMismatch {
+		respondVersionMismatch(c, id)
+		return
+	} else if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not update item in the database",
+		})
+		return
+	}
+
+	decodedItem, err := item.Decode()
 	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode an item",
+		})
 		return
 	}
-	defer rows.Close()
 
-	var items []Item
+	c.Header("ETag", item.ETag())
+	c.JSON(200, gin.H{
+		"status":  "ok",
+		"message": "successfully patched item",
+		"version": item.Version,
+		"item":    decodedItem,
+	})
+}
 
-	for rows.Next() {
-		var item Item
+// deleteItem moves an item (event or location) to the trash
+func deleteItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": errors.New("ID is not valid"),
+		})
+		return
+	}
 
-		if err = rows.Scan(
-			&item.ID,
-			&item.Data,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		); err != nil {
-			log.Error(err)
-			c.JSON(500, gin.H{
-				"status":  "error",
-				"message": "could not fetch an item",
-			})
+	item, err := itemStore.Get(int64(id))
+	if err == nil {
+		if !checkItemType(c, itemType(item.Data)) {
 			return
 		}
 
-		items = append(items, item)
+		version, ok := checkIfMatch(c, item)
+		if !ok {
+			return
+		}
+
+		err = itemStore.Delete(item.ID, version)
+	}
+
+	if err == ErrVersionMismatch {
+		respondVersionMismatch(c, item.ID)
+		return
+	} else if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not delete item from the database",
+		})
+		return
+	}
+
+	// The item stays in the trash, but it should disappear from the site right away
+	if err := removeGeneratedContent(item); err != nil {
+		log.Warn("Could not remove generated content of item ", item.ID, ": ", err)
+	}
+
+	c.JSON(200, gin.H{
+		"status":  "ok",
+		"message": "successfully moved item to the trash",
+	})
+}
+
+// postGenerate generates markdown pages and places assets in the Zola directory
+// Recurring events get a page for each upcoming occurrence with "?pages=occurrence".
+func postGenerate(c *gin.Context) {
+	typ := c.Param("typ")
+
+	if !checkItemType(c, typ) {
+		return
+	}
+
+	pages := c.DefaultQuery("pages", eventPages)
+	if pages != eventPagesSeries && pages != eventPagesOccurrence {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "pages must be series or occurrence",
+		})
+		return
+	}
+
+	items, err := itemStore.ListByType(typ)
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch items",
+		})
+		return
 	}
 
+	// Every page is generated as of the same time
+	now := time.Now()
+
 	for _, item := range items {
 		var itemCommonData ItemCommonData
 
@@ -412,7 +733,7 @@ func postGenerate(c *gin.Context) {
 				return
 			}
 
-			if err := generateLocationContent(location); err != nil {
+			if err := generateLocationContent(location, now); err != nil {
 				log.Error(err)
 				c.JSON(500, gin.H{
 					"status":  "error",
@@ -431,7 +752,7 @@ func postGenerate(c *gin.Context) {
 				return
 			}
 
-			if err := generateEventContent(event); err != nil {
+			if err := generateEventContent(event, pages); err != nil {
 				log.Error(err)
 				c.JSON(500, gin.H{
 					"status":  "error",
@@ -455,14 +776,15 @@ func postGenerate(c *gin.Context) {
 	})
 }
 
-func serveAPI(c *cli.Context) error {
+// newRouter sets up the routes of the administration API
+func newRouter() *gin.Engine {
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "If-Match", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: false,
 		AllowOriginFunc: func(origin string) bool {
 			return origin == "http://localhost:8000"
@@ -470,30 +792,98 @@ func serveAPI(c *cli.Context) error {
 		MaxAge: 12 * time.Hour,
 	}))
 
-	r.GET("/items", getItems)
+	// Sign in and out
+	r.POST("/login", postLogin)
+
+	// Let calendar apps subscribe to the events
+	r.GET("/feeds/events.ics", getEventsFeed)
+
+	authed := r.Group("/", authenticate)
+	authed.POST("/logout", postLogout)
+	authed.GET("/me", getMe)
+
+	viewer := authed.Group("/", requireRole(RoleViewer))
+	editor := authed.Group("/", requireRole(RoleEditor))
+	publisher := authed.Group("/", requireRole(RolePublisher))
+	admin := authed.Group("/", requireRole(RoleAdmin))
+
+	viewer.GET("/items", getItems)
+
+	// List the items around a point, closest first
+	viewer.GET("/items/nearby", getNearbyItems)
 
 	// Get a single item (event or location)
-	r.GET("/item/:id", getItem)
+	viewer.GET("/item/:id", getItem)
 
 	// Create a new item (event or location)
-	r.POST("/item", postItem)
+	editor.POST("/item", postItem)
 
 	// Update an existing item (event or location)
-	r.PUT("/item/:id", putItem)
+	editor.PUT("/item/:id", putItem)
+
+	// Partially update an existing item (event or location)
+	editor.PATCH("/item/:id", patchItem)
 
 	// Delete an existing item (event or location)
-	r.DELETE("/item/:id", deleteItem)
+	editor.DELETE("/item/:id", deleteItem)
+
+	// Browse the revision history of an item and restore earlier revisions
+	viewer.GET("/item/:id/revisions", getItemRevisions)
+	viewer.GET("/item/:id/revisions/:rev", getItemRevision)
+	viewer.GET("/item/:id/revisions/:rev/diff", getItemRevisionDiff)
+	editor.POST("/item/:id/revisions/:rev/restore", postItemRevisionRestore)
+
+	// List and restore deleted items
+	viewer.GET("/trash", getTrash)
+	editor.POST("/item/:id/restore", postItemRestore)
+
+	// List when events take place, with recurring events expanded into their occurrences
+	viewer.GET("/events/occurrences", getEventOccurrences)
+
+	// Tell which locations are open at a time, and when they next open or close
+	viewer.GET("/locations/open", getLocationsOpen)
 
 	// Run the static site content generator
-	r.POST("/generate/:typ", postGenerate)
+	publisher.POST("/generate/:typ", postGenerate)
 
-	// Dummy cover image endpoint
-	r.POST("/cover", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "successfully uploaded cover image",
-		})
-	})
+	// Upload an image that items can refer to by its ID
+	editor.POST("/images", postImage)
+	viewer.GET("/images/:id/meta", getImageMeta)
+
+	// List stored images that still reveal where they were taken
+	admin.GET("/admin/images/location-report", getImageLocationReport)
+
+	return r
+}
+
+func serveAPI(c *cli.Context) error {
+	if err := openStores(c.String("store")); err != nil {
+		return err
+	}
+
+	if err := openBlobStore(c); err != nil {
+		return err
+	}
+
+	authEnabled = !c.Bool("no-auth")
+	if !authEnabled {
+		log.Warn("Authentication is disabled, anyone who can reach the server has full access")
+	}
+
+	sessionLifetime = c.Duration("session-lifetime")
+	maxImageSize = c.Int64("max-image-size")
+
+	eventPages = c.String("event-pages")
+	if eventPages != eventPagesSeries && eventPages != eventPagesOccurrence {
+		return fmt.Errorf("unknown event pages \"%s\", must be series or occurrence", eventPages)
+	}
+	occurrenceHorizon = c.Duration("occurrence-horizon")
+
+	if interval := c.Duration("gc-interval"); interval > 0 {
+		go runImageCollector(interval, c.Duration("gc-grace"), c.Duration("gc-revision-retention"))
+	}
+
+	r := newRouter()
 
 	log.Info("Content will be generated at \"", c.String("zola-path"), "\"")
 
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	authEnabled     = true           // Whether requests must carry a valid session token
+	sessionLifetime = 24 * time.Hour // How long a session lasts after signing in
+)
+
+// bearerToken reads the token from the "Authorization: Bearer <token>" header of a request
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// authenticate is a middleware that looks up the user of the session token in the request,
+// or the API key if the token is one
+func authenticate(c *gin.Context) {
+	if !authEnabled {
+		return
+	}
+
+	token := bearerToken(c)
+	if token == "" {
+		c.AbortWithStatusJSON(401, gin.H{
+			"status":  "error",
+			"message": "authentication is required",
+		})
+		return
+	}
+
+	if strings.HasPrefix(token, apiKeyTokenPrefix) {
+		authenticateAPIKey(c, token)
+		return
+	}
+
+	user, err := userStore.GetSessionUser(hashToken(token))
+	if err == ErrSessionNotFound {
+		c.AbortWithStatusJSON(401, gin.H{
+			"status":  "error",
+			"message": "session is not valid or has expired",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.AbortWithStatusJSON(500, gin.H{
+			"status":  "error",
+			"message": "could not check the session",
+		})
+		return
+	}
+
+	c.Set("user", user)
+}
+
+// authenticateAPIKey looks up the API key used to make a request
+func authenticateAPIKey(c *gin.Context, token string) {
+	key, err := apiKeyStore.GetAPIKey(hashToken(token))
+	if err == ErrAPIKeyNotFound {
+		c.AbortWithStatusJSON(401, gin.H{
+			"status":  "error",
+			"message": "API key is not valid, has expired or was revoked",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.AbortWithStatusJSON(500, gin.H{
+			"status":  "error",
+			"message": "could not check the API key",
+		})
+		return
+	}
+
+	if err := apiKeyStore.TouchAPIKey(key.ID); err != nil {
+		log.Warn("Could not record the use of API key ", key.Prefix, ": ", err)
+	}
+
+	c.Set("apiKey", key)
+}
+
+// requireRole is a middleware that only lets through users with at least the given role.
+// API keys are let through when they allow the action matching the role.
+// It must come after authenticate.
+func requireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authEnabled {
+			return
+		}
+
+		if key, ok := requestAPIKey(c); ok {
+			if action, ok := roleActions[role]; !ok || !key.Allows(action) {
+				c.AbortWithStatusJSON(403, gin.H{
+					"status":  "error",
+					"message": "this API key is not allowed to do this",
+				})
+			}
+			return
+		}
+
+		user := c.MustGet("user").(User)
+		if !user.Role.Includes(role) {
+			c.AbortWithStatusJSON(403, gin.H{
+				"status":  "error",
+				"message": "this requires the " + string(role) + " role",
+			})
+			return
+		}
+	}
+}
+
+// requestAPIKey returns the API key used to make a request, if any
+func requestAPIKey(c *gin.Context) (key APIKey, ok bool) {
+	v, ok := c.Get("apiKey")
+	if ok {
+		key = v.(APIKey)
+	}
+	return
+}
+
+// checkItemType reports whether the request may access items of type typ.
+// It responds with 403 and returns false when the request was made with an API key
+// that is not scoped to that type.
+func checkItemType(c *gin.Context, typ string) bool {
+	if key, ok := requestAPIKey(c); ok && !key.AllowsType(typ) {
+		c.JSON(403, gin.H{
+			"status":  "error",
+			"message": "this API key is not allowed to access items of type \"" + typ + "\"",
+		})
+		return false
+	}
+
+	return true
+}
+
+// scopeItemTypes returns the item types a listing of items of type typ, or of every type when typ is empty,
+// is limited to. Keys scoped to some item types only see items of those types.
+// It responds with 403 and returns false when the request may not access items of type typ.
+func scopeItemTypes(c *gin.Context, typ string) (types []string, ok bool) {
+	key, isKey := requestAPIKey(c)
+	if !isKey {
+		return nil, true
+	}
+
+	if typ != "" {
+		return nil, checkItemType(c, typ)
+	}
+
+	return key.ItemTypes, true
+}
+
+// postLogin signs a user in and returns a new session token
+func postLogin(c *gin.Context) {
+	var credentials struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "username and password are required",
+		})
+		return
+	}
+
+	user, err := userStore.GetUser(credentials.Username)
+	if err != nil && err != ErrUserNotFound {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch the user",
+		})
+		return
+	}
+
+	passwordHash := user.PasswordHash
+	if err == ErrUserNotFound {
+		passwordHash = dummyPasswordHash
+	}
+
+	if !checkPassword(passwordHash, credentials.Password) || err == ErrUserNotFound {
+		c.JSON(401, gin.H{
+			"status":  "error",
+			"message": "username or password is not correct",
+		})
+		return
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not create a session",
+		})
+		return
+	}
+
+	expiresAt := time.Now().Add(sessionLifetime)
+
+	if err := userStore.CreateSession(user.ID, tokenHash, expiresAt); err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not create a session",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":    "ok",
+		"message":   "successfully signed in",
+		"token":     token,
+		"expiresAt": expiresAt,
+		"user":      user,
+	})
+}
+
+// postLogout ends the session used to make the request
+func postLogout(c *gin.Context) {
+	if err := userStore.DeleteSession(hashToken(bearerToken(c))); err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not end the session",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":  "ok",
+		"message": "successfully signed out",
+	})
+}
+
+// getMe returns the user or the API key that made the request
+func getMe(c *gin.Context) {
+	if key, ok := requestAPIKey(c); ok {
+		c.JSON(200, gin.H{"apiKey": key})
+		return
+	}
+
+	user, ok := c.Get("user")
+	if !ok {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "authentication is disabled",
+		})
+		return
+	}
+
+	c.JSON(200, user)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRouter sets up a router backed by memory stores and a blob store holding an image with the ID "abc"
+func newTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	itemStore = NewMemoryItemStore()
+	userStore = NewMemoryUserStore()
+	apiKeyStore = NewMemoryAPIKeyStore()
+	authEnabled = true
+
+	blobStore = NewFSBlobStore(t.TempDir())
+	image := testJPEG(t, 4, 4)
+	if err := blobStore.Put(BlobInfo{Key: "abc", Size: int64(len(image)), ContentType: "image/jpeg"}, bytes.NewReader(image)); err != nil {
+		t.Fatal(err)
+	}
+
+	return newRouter()
+}
+
+// testRequest sends a request to r and returns the recorded response
+func testRequest(r http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// testLogin creates a user with the given role and returns a session token for them
+func testLogin(t *testing.T, r http.Handler, username string, role Role) string {
+	passwordHash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = userStore.CreateUser(username, passwordHash, role); err != nil {
+		t.Fatal(err)
+	}
+
+	w := testRequest(r, "POST", "/login", "", `{"username": "`+username+`", "password": "correct horse"}`)
+	if w.Code != 200 {
+		t.Fatalf("login failed: %s", w.Body)
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	return response.Token
+}
+
+func TestAuthRoles(t *testing.T) {
+	r := newTestRouter(t)
+
+	viewer := testLogin(t, r, "viv", RoleViewer)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	assert.Equal(t, 401, testRequest(r, "GET", "/items", "", "").Code)
+	assert.Equal(t, 401, testRequest(r, "GET", "/items", "not-a-token", "").Code)
+	assert.Equal(t, 200, testRequest(r, "GET", "/items", viewer, "").Code)
+
+	item := `{"type": "location", "title": "Cafe", "coverImageURL": "abc"}`
+	assert.Equal(t, 403, testRequest(r, "POST", "/item", viewer, item).Code)
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, item).Code)
+	assert.Equal(t, 403, testRequest(r, "POST", "/generate/location", editor, "").Code)
+
+	assert.Equal(t, 200, testRequest(r, "POST", "/logout", editor, "").Code)
+	assert.Equal(t, 401, testRequest(r, "GET", "/items", editor, "").Code)
+}
+
+func TestAuthWrongPassword(t *testing.T) {
+	r := newTestRouter(t)
+	testLogin(t, r, "viv", RoleViewer)
+
+	assert.Equal(t, 401, testRequest(r, "POST", "/login", "", `{"username": "viv", "password": "wrong password"}`).Code)
+	assert.Equal(t, 401, testRequest(r, "POST", "/login", "", `{"username": "nobody", "password": "correct horse"}`).Code)
+}
+
+// testAPIKey creates an API key with the given scopes and returns its token
+func testAPIKey(t *testing.T, itemTypes, actions []string) string {
+	key, token, err := newAPIKey("test", itemTypes, actions, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = apiKeyStore.CreateAPIKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	r := newTestRouter(t)
+
+	events := testAPIKey(t, []string{"event"}, []string{ActionRead, ActionWrite})
+	reader := testAPIKey(t, nil, []string{ActionRead})
+
+	location := `{"type": "location", "title": "Cafe", "coverImageURL": "abc"}`
+	event := `{"type": "event", "title": "Fair", "coverImageURL": "abc"}`
+
+	assert.Equal(t, 403, testRequest(r, "POST", "/item", events, location).Code)
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", events, event).Code)
+	assert.Equal(t, 403, testRequest(r, "POST", "/item", reader, event).Code)
+	assert.Equal(t, 403, testRequest(r, "POST", "/generate/event", events, "").Code)
+	assert.Equal(t, 403, testRequest(r, "GET", "/items?type=location", events, "").Code)
+
+	itemStore.Create([]byte(location))
+
+	var page struct {
+		Total int `json:"total"`
+	}
+
+	w := testRequest(r, "GET", "/items", events, "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, 1, page.Total)
+
+	w = testRequest(r, "GET", "/items", reader, "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, 2, page.Total)
+
+	assert.Equal(t, 403, testRequest(r, "GET", "/item/2", events, "").Code)
+	assert.Equal(t, 200, testRequest(r, "GET", "/item/2", reader, "").Code)
+}
+
+func TestAPIKeyRevokeAndExpiry(t *testing.T) {
+	r := newTestRouter(t)
+
+	token := testAPIKey(t, nil, []string{ActionRead})
+	assert.Equal(t, 200, testRequest(r, "GET", "/items", token, "").Code)
+
+	keys, _ := apiKeyStore.ListAPIKeys()
+	assert.NotNil(t, keys[0].LastUsedAt)
+	assert.NotContains(t, string(keys[0].KeyHash), token)
+
+	assert.Nil(t, apiKeyStore.RevokeAPIKey(keys[0].Prefix))
+	assert.Equal(t, 401, testRequest(r, "GET", "/items", token, "").Code)
+
+	key, token, err := newAPIKey("expired", nil, []string{ActionRead}, time.Hour)
+	assert.Nil(t, err)
+	expiresAt := time.Now().Add(-time.Minute)
+	key.ExpiresAt = &expiresAt
+	apiKeyStore.CreateAPIKey(key)
+	assert.Equal(t, 401, testRequest(r, "GET", "/items", token, "").Code)
+
+	_, _, err = newAPIKey("bad", []string{"museum"}, []string{ActionRead}, 0)
+	assert.NotNil(t, err)
+	_, _, err = newAPIKey("bad", nil, []string{"delete"}, 0)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkIfMatch compares the If-Match header of a request with the current version of item.
+// It returns the version that the change must be applied to, which is 0 when the
+// request is unconditional. When the header doesn't match, it responds with 412
+// and returns false.
+func checkIfMatch(c *gin.Context, item Item) (version int64, ok bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, true
+	}
+
+	if header == "*" {
+		return item.Version, true
+	}
+
+	etag := item.ETag()
+	for _, candidate := range strings.Split(header, ",") {
+		// Weak tags never match for If-Match, as required by RFC 7232
+		if strings.TrimSpace(candidate) == etag {
+			return item.Version, true
+		}
+	}
+
+	respondPreconditionFailed(c, item)
+	return 0, false
+}
+
+// respondPreconditionFailed tells the client that the item was changed by someone else.
+// The current version of the item is included so that the client can merge the changes.
+func respondPreconditionFailed(c *gin.Context, item Item) {
+	decodedItem, err := item.Decode()
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode an item",
+		})
+		return
+	}
+
+	c.Header("ETag", item.ETag())
+	c.JSON(412, gin.H{
+		"status":  "error",
+		"message": "item has been modified since it was fetched",
+		"version": item.Version,
+		"item":    decodedItem,
+	})
+}
+
+// respondVersionMismatch handles ErrVersionMismatch returned by the store,
+// which happens when the item changed between checking If-Match and saving
+func respondVersionMismatch(c *gin.Context, id int64) {
+	item, err := itemStore.Get(id)
+	if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch an item",
+		})
+		return
+	}
+
+	respondPreconditionFailed(c, item)
+}
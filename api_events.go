@@ -0,0 +1,205 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOccurrenceWindow = 31 * 24 * time.Hour  // How far ahead occurrences are listed when "to" is left out
+	maxOccurrenceWindow     = 366 * 24 * time.Hour // The longest window occurrences can be listed for at once
+)
+
+// EventOccurrence is a single time that an event takes place at
+type EventOccurrence struct {
+	EventID      int64     `json:"eventId"`
+	Title        string    `json:"title"`
+	SessionTitle string    `json:"sessionTitle,omitempty"`
+	StartsAt     time.Time `json:"startsAt"`
+	EndsAt       time.Time `json:"endsAt"`
+	AllDay       bool      `json:"allDay"`
+	TimeZone     string    `json:"timeZone"`
+}
+
+// timeParam reads an RFC 3339 time or a date, taken as midnight UTC, from the query string.
+// It responds with 400 and returns false when the value is not valid.
+func timeParam(c *gin.Context, name string, defaultValue time.Time) (t time.Time, ok bool) {
+	s := c.Query(name)
+	if s == "" {
+		return defaultValue, true
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, err = time.Parse(eventDateLayout, s); err != nil {
+			c.JSON(400, gin.H{
+				"status":  "error",
+				"message": name + " must be a time like 2006-01-02T15:04:05Z or a date like 2006-01-02",
+			})
+			return
+		}
+	}
+
+	return t, true
+}
+
+// getEventOccurrences lists the occurrences of all events between "from" and "to" in order.
+// Recurring events are expanded into each of their occurrences.
+func getEventOccurrences(c *gin.Context) {
+	if !checkItemType(c, "event") {
+		return
+	}
+
+	from, ok := timeParam(c, "from", time.Now())
+	if !ok {
+		return
+	}
+
+	to, ok := timeParam(c, "to", from.Add(defaultOccurrenceWindow))
+	if !ok {
+		return
+	}
+
+	if !to.After(from) {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "to must be after from",
+		})
+		return
+	} else if to.Sub(from) > maxOccurrenceWindow {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "occurrences can be listed for at most a year at once",
+		})
+		return
+	}
+
+	items, err := itemStore.ListByType("event")
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch items",
+		})
+		return
+	}
+
+	occurrences := make([]EventOccurrence, 0)
+
+	for _, item := range items {
+		event, err := EventFromItem(item)
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not convert internal JSON into event structure",
+			})
+			return
+		}
+
+		// Events stored before their schedule was validated may not have a usable one
+		periods, err := event.Occurrences(from, to)
+		if err != nil {
+			log.Warn("Could not list occurrences of event ", event.ID, ": ", err)
+			continue
+		}
+
+		for _, period := range periods {
+			occurrences = append(occurrences, EventOccurrence{
+				EventID:      event.ID,
+				Title:        event.Title,
+				SessionTitle: period.Title,
+				StartsAt:     period.Start,
+				EndsAt:       period.End,
+				AllDay:       event.AllDay,
+				TimeZone:     event.TimeZone,
+			})
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		if !occurrences[i].StartsAt.Equal(occurrences[j].StartsAt) {
+			return occurrences[i].StartsAt.Before(occurrences[j].StartsAt)
+		}
+		return occurrences[i].EventID < occurrences[j].EventID
+	})
+
+	c.JSON(200, gin.H{
+		"from":        from,
+		"to":          to,
+		"occurrences": occurrences,
+	})
+}
+
+// getEventsFeed returns the events as an iCalendar feed that calendar apps can subscribe to,
+// limited to the events with the "tag" in the query string if there is one.
+// Calendar apps can't sign in, so the feed is public like the site the events are published on.
+func getEventsFeed(c *gin.Context) {
+	tag := c.Query("tag")
+
+	items, err := itemStore.ListByType("event")
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch items",
+		})
+		return
+	}
+
+	events := make([]Event, 0, len(items))
+
+	for _, item := range items {
+		event, err := EventFromItem(item)
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not convert internal JSON into event structure",
+			})
+			return
+		}
+
+		if tag != "" && !hasTag(event.Tags, tag) {
+			continue
+		}
+
+		// Events stored before their schedule was validated may not have a usable one
+		if _, err := event.Occurrences(time.Time{}, time.Time{}); err != nil {
+			log.Warn("Could not add event ", event.ID, " to the feed: ", err)
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	name := "Events"
+	if tag != "" {
+		name += " tagged " + tag
+	}
+
+	data, err := eventCalendar(name, events, time.Now())
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not create the feed",
+		})
+		return
+	}
+
+	c.Data(200, "text/calendar; charset=utf-8", data)
+}
+
+// hasTag reports whether tags contains tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
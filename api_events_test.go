@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventOccurrences(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	market := `{"type": "event", "title": "Market", "coverImageURL": "abc", "startsAt": "2026-05-02T08:00", "endsAt": "2026-05-02T13:00",
+		"timeZone": "Asia/Singapore", "rrule": "FREQ=WEEKLY;COUNT=3"}`
+	talk := `{"type": "event", "title": "Talk", "coverImageURL": "abc", "startsAt": "2026-05-09T19:00", "timeZone": "Europe/London"}`
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, market).Code)
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, talk).Code)
+
+	w := testRequest(r, "GET", "/events/occurrences?from=2026-05-01&to=2026-06-01", editor, "")
+	if !assert.Equal(t, 200, w.Code) {
+		return
+	}
+
+	var response struct {
+		Occurrences []EventOccurrence `json:"occurrences"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	var titles []string
+	for _, occurrence := range response.Occurrences {
+		titles = append(titles, occurrence.Title+" "+occurrence.StartsAt.UTC().Format(eventTimeLayout))
+	}
+	assert.Equal(t, []string{
+		"Market 2026-05-02T00:00",
+		"Market 2026-05-09T00:00",
+		"Talk 2026-05-09T18:00",
+		"Market 2026-05-16T00:00",
+	}, titles)
+
+	assert.Equal(t, 400, testRequest(r, "GET", "/events/occurrences?from=2026-05-01&to=2028-01-01", editor, "").Code)
+	assert.Equal(t, 400, testRequest(r, "GET", "/events/occurrences?from=yesterday", editor, "").Code)
+
+	invalid := `{"type": "event", "title": "Quiz", "coverImageURL": "abc", "rrule": "FREQ=WEEKLY"}`
+	assert.Equal(t, 422, testRequest(r, "POST", "/item", editor, invalid).Code)
+}
+
+func TestGenerateEventOccurrencePages(t *testing.T) {
+	itemStore = NewMemoryItemStore()
+	blobStore = NewFSBlobStore(t.TempDir())
+	zolaPath = t.TempDir()
+	defer func() { zolaPath = "" }()
+
+	os.MkdirAll(filepath.Join(zolaPath, "content", "events"), 0700)
+
+	photo := testJPEG(t, 8, 8)
+	blobStore.Put(BlobInfo{Key: "photo", Size: int64(len(photo)), ContentType: "image/jpeg"}, bytes.NewReader(photo))
+
+	event := Event{ID: 4, Title: "Meetup", CoverImageURL: "photo", StartsAt: "2020-01-06", AllDay: true,
+		TimeZone: "UTC", RRule: "FREQ=DAILY", ExDates: []string{"2020-01-07"}}
+	occurrenceHorizon = 48 * time.Hour
+	defer func() { occurrenceHorizon = 90 * 24 * time.Hour }()
+
+	assert.NoError(t, generateEventContent(event, eventPagesSeries))
+	page, _ := os.ReadFile(filepath.Join(zolaPath, "content", "events", "4.md"))
+	assert.Contains(t, string(page), `rrule = "FREQ=DAILY"`)
+
+	// Today is still going on, so it is listed along with the next two days
+	assert.Equal(t, 3, bytes.Count(page, []byte("[[extra.occurrences]]")))
+
+	assert.NoError(t, generateEventContent(event, eventPagesOccurrence))
+	pages, _ := filepath.Glob(filepath.Join(zolaPath, "content", "events", "*.md"))
+	assert.Len(t, pages, 3)
+
+	calendar, err := os.ReadFile(filepath.Join(zolaPath, "content", "events", "4.ics"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(calendar), "EXDATE;VALUE=DATE:20200107\r\n")
+
+	assert.NoError(t, removeGeneratedContent(Item{ID: 4, Data: []byte(`{"type": "event", "coverImageURL": "photo"}`)}))
+	pages, _ = filepath.Glob(filepath.Join(zolaPath, "content", "events", "*"))
+	assert.Empty(t, pages)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxImageSize is the largest image that can be uploaded, in bytes
+var maxImageSize = int64(10 << 20)
+
+var (
+	// errImageEmpty is returned by receiveImage when an upload has no content
+	errImageEmpty = errors.New("image is empty")
+
+	// errImageTooLarge is returned by receiveImage when an upload exceeds maxImageSize
+	errImageTooLarge = errors.New("image is too large")
+)
+
+// receiveImage streams an uploaded image into a temporary file while hashing it,
+// then stores it in blobStore without its EXIF and XMP metadata. The image is named
+// after the hex SHA-256 hash of the uploaded content, which is returned as its ID.
+func receiveImage(r io.Reader) (id, contentType string, size int64, err error) {
+	// Read enough to sniff the content type before anything is written
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			err = errImageEmpty
+		}
+		return
+	}
+	head = head[:n]
+
+	contentType = detectImageType(head)
+	if _, ok := imageExtensions[contentType]; !ok {
+		err = errImageType
+		return
+	}
+
+	tmp, err := ioutil.TempFile("", "ttd-upload-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	w := io.MultiWriter(tmp, hash)
+
+	// Read one byte past the limit to tell a full-sized image from one that is too large
+	size, err = io.Copy(w, io.LimitReader(io.MultiReader(bytes.NewReader(head), r), maxImageSize+1))
+	if err != nil {
+		return
+	}
+
+	if size > maxImageSize {
+		err = errImageTooLarge
+		return
+	}
+
+	id = hex.EncodeToString(hash.Sum(nil))
+
+	// The same upload always gets the same ID, so an existing blob can be kept as it is
+	if info, statErr := blobStore.Stat(id); statErr == nil {
+		contentType, size = info.ContentType, info.Size
+		return
+	} else if statErr != ErrBlobNotFound {
+		err = statErr
+		return
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	data, err := ioutil.ReadAll(tmp)
+	if err != nil {
+		return
+	}
+
+	// Photos often carry the location they were taken at, which must not end up on the site
+	if data, contentType, err = stripImageMetadata(data, contentType); err != nil {
+		return
+	}
+	size = int64(len(data))
+
+	metadata, err := describeImage(data, contentType)
+	if err != nil {
+		return
+	}
+
+	err = blobStore.Put(BlobInfo{Key: id, Size: size, ContentType: contentType, Metadata: metadata}, bytes.NewReader(data))
+	return
+}
+
+// postImage receives an image uploaded as the "image" field of a multipart form.
+// The returned ID can be used in coverImageURL and imageURLs of items.
+func postImage(c *gin.Context) {
+	// Leave some room for the rest of the multipart body
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageSize+64<<10)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "request must be multipart/form-data",
+		})
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			c.JSON(400, gin.H{
+				"status":  "error",
+				"message": "could not read the multipart form",
+			})
+			return
+		}
+
+		if part.FormName() != "image" {
+			part.Close()
+			continue
+		}
+
+		id, contentType, size, err := receiveImage(part)
+		part.Close()
+
+		switch {
+		case err == errImageEmpty:
+			c.JSON(400, gin.H{
+				"status":  "error",
+				"message": "image is empty",
+			})
+			return
+		case err == errImageTooLarge:
+			c.JSON(413, gin.H{
+				"status":  "error",
+				"message": fmt.Sprintf("image must not be larger than %d bytes", maxImageSize),
+			})
+			return
+		case err == errImageType:
+			c.JSON(415, gin.H{
+				"status":  "error",
+				"message": errImageType.Error(),
+			})
+			return
+		case err == errImageDimensions:
+			c.JSON(422, gin.H{
+				"status":  "error",
+				"message": errImageDimensions.Error(),
+			})
+			return
+		case err != nil:
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not store the image",
+			})
+			return
+		}
+
+		c.JSON(200, gin.H{
+			"status":      "ok",
+			"message":     "successfully uploaded image",
+			"id":          id,
+			"contentType": contentType,
+			"size":        size,
+		})
+		return
+	}
+
+	c.JSON(400, gin.H{
+		"status":  "error",
+		"message": "image field is required",
+	})
+}
+
+// getImageMeta describes a stored image
+func getImageMeta(c *gin.Context) {
+	meta, err := loadImageMeta(c.Param("id"))
+	if err == ErrBlobNotFound || err == errImageType {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "image not found",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not describe the image",
+		})
+		return
+	}
+
+	c.JSON(200, meta)
+}
+
+// getImageLocationReport lists the stored images that still carry GPS coordinates in their metadata,
+// such as those stored before metadata was stripped on upload
+func getImageLocationReport(c *gin.Context) {
+	infos, err := blobStore.List("")
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not list images",
+		})
+		return
+	}
+
+	blobs := make([]BlobInfo, 0)
+
+	for _, info := range infos {
+		// Derivatives are encoded from pixels only and never carry metadata
+		if strings.HasPrefix(info.Key, "derivatives/") {
+			continue
+		}
+
+		data, err := readBlob(blobStore, info.Key)
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not read an image",
+			})
+			return
+		}
+
+		contentType := detectImageType(data)
+		metadata, err := readImageMetadata(data, contentType)
+		if err != nil {
+			log.Warn("Could not read the metadata of image ", info.Key, ": ", err)
+			continue
+		}
+
+		if metadata.HasLocation {
+			info.ContentType = contentType
+			blobs = append(blobs, info)
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"blobs": blobs,
+		"total": len(blobs),
+	})
+}
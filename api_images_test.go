@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testUpload sends data as the "image" field of a multipart form to POST /images
+func testUpload(t *testing.T, r http.Handler, token string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/images", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// testPNG encodes a small blank PNG image
+func testPNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPostImage(t *testing.T) {
+	r := newTestRouter(t)
+
+	editor := testLogin(t, r, "eddie", RoleEditor)
+	data := testPNG(t)
+
+	w := testUpload(t, r, editor, data)
+	assert.Equal(t, 200, w.Code)
+
+	var response struct {
+		ID          string `json:"id"`
+		ContentType string `json:"contentType"`
+		Size        int64  `json:"size"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	assert.Equal(t, "image/png", response.ContentType)
+	assert.Equal(t, int64(len(data)), response.Size)
+
+	stored, err := readBlob(blobStore, response.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, data, stored)
+
+	info, err := blobStore.Stat(response.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", info.ContentType)
+
+	// Uploading the same image again returns the same ID
+	w = testUpload(t, r, editor, data)
+	assert.Contains(t, w.Body.String(), response.ID)
+
+	item := `{"type": "location", "title": "Cafe", "coverImageURL": "` + response.ID + `"}`
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, item).Code)
+
+	assert.Equal(t, 415, testUpload(t, r, editor, []byte("not an image at all")).Code)
+	assert.Equal(t, 400, testUpload(t, r, editor, nil).Code)
+
+	// Small files can still decode into more pixels than are allowed
+	maxImagePixels = 15
+	defer func() { maxImagePixels = 50000000 }()
+	assert.Equal(t, 422, testUpload(t, r, editor, testJPEG(t, 4, 4)).Code)
+
+	inline := `{"type": "location", "title": "Bar", "coverImageURL": "data:image/jpeg;base64,` + base64.StdEncoding.EncodeToString(testJPEG(t, 4, 4)) + `"}`
+	assert.Equal(t, 422, testRequest(r, "POST", "/item", editor, inline).Code)
+
+	maxImageSize = int64(len(data) - 1)
+	defer func() { maxImageSize = 10 << 20 }()
+	assert.Equal(t, 413, testUpload(t, r, editor, data).Code)
+
+	// Nothing but the stored image and the one the test router starts with is left behind
+	blobs, _ := blobStore.List("")
+	assert.Len(t, blobs, 2)
+}
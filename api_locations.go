@@ -0,0 +1,97 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// LocationOpenStatus is whether a location is open at an instant, and when it next opens or closes.
+// Error is set instead when that can't be told, such as for locations stored before their opening hours were validated.
+type LocationOpenStatus struct {
+	LocationID int64  `json:"locationId"`
+	Title      string `json:"title"`
+	Error      string `json:"error,omitempty"`
+	*OpenStatus
+}
+
+// getLocationsOpen tells for every location whether it is open at "at", which defaults to now.
+// Opening hours are taken in the time zone of each location, or in the time zone "tz" for the locations
+// that have none, which defaults to the time zone of the server.
+func getLocationsOpen(c *gin.Context) {
+	if !checkItemType(c, "location") {
+		return
+	}
+
+	at, ok := timeParam(c, "at", time.Now())
+	if !ok {
+		return
+	}
+
+	fallback := time.Local
+	if tz := c.Query("tz"); tz != "" {
+		var err error
+		if fallback, err = time.LoadLocation(tz); err != nil {
+			c.JSON(400, gin.H{
+				"status":  "error",
+				"message": "tz must be a time zone like Asia/Singapore",
+			})
+			return
+		}
+	}
+
+	items, err := itemStore.ListByType("location")
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch items",
+		})
+		return
+	}
+
+	locations := make([]LocationOpenStatus, 0, len(items))
+
+	for _, item := range items {
+		location, err := LocationFromItem(item)
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not convert internal JSON into location structure",
+			})
+			return
+		}
+
+		locationStatus := LocationOpenStatus{
+			LocationID: location.ID,
+			Title:      location.Title,
+		}
+
+		loc, err := location.TimeLocation(fallback)
+		if err != nil {
+			log.Warn("Could not load the time zone of location ", location.ID, ": ", err)
+			locationStatus.Error = "could not load the time zone: " + err.Error()
+			locations = append(locations, locationStatus)
+			continue
+		}
+
+		// Locations stored before their opening hours were validated may not have usable ones
+		status, err := location.OpenStatus(at, loc)
+		if err != nil {
+			log.Warn("Could not tell whether location ", location.ID, " is open: ", err)
+			locationStatus.Error = "could not read the opening hours: " + err.Error()
+			locations = append(locations, locationStatus)
+			continue
+		}
+
+		locationStatus.OpenStatus = &status
+		locations = append(locations, locationStatus)
+	}
+
+	c.JSON(200, gin.H{
+		"at":        at,
+		"locations": locations,
+	})
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// itemIDParam reads the ":id" parameter of a request.
+// It responds with 400 and returns false when the ID is not valid.
+func itemIDParam(c *gin.Context) (id int64, ok bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "ID is not valid",
+		})
+		return
+	}
+
+	return id, true
+}
+
+// revisionParam reads a revision number from a request parameter.
+// It responds with 400 and returns false when the number is not valid.
+func revisionParam(c *gin.Context, s string) (revision int, ok bool) {
+	revision, err := strconv.Atoi(s)
+	if err != nil || revision < 1 {
+		c.JSON(400, gin.H{
+			"status":  "error",
+			"message": "revision is not valid",
+		})
+		return
+	}
+
+	return revision, true
+}
+
+// respondRevisionError responds to errors returned by the revision methods of ItemStore
+func respondRevisionError(c *gin.Context, err error, message string) {
+	switch err {
+	case ErrItemNotFound:
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item not found",
+		})
+	case ErrRevisionNotFound:
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "revision not found",
+		})
+	default:
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": message,
+		})
+	}
+}
+
+// liveOrTrashedItem fetches an item whether or not it is in the trash
+func liveOrTrashedItem(id int64) (Item, error) {
+	item, err := itemStore.Get(id)
+	if err != ErrItemNotFound {
+		return item, err
+	}
+
+	trash, err := itemStore.ListTrash()
+	if err != nil {
+		return Item{}, err
+	}
+
+	for _, item := range trash {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+
+	return Item{}, ErrItemNotFound
+}
+
+// getItemRevisions lists the revision history of an item
+func getItemRevisions(c *gin.Context) {
+	id, ok := itemIDParam(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := itemStore.ListRevisions(id)
+	if err != nil {
+		respondRevisionError(c, err, "could not fetch revisions")
+		return
+	}
+
+	if len(revisions) > 0 && !checkItemType(c, itemType(revisions[len(revisions)-1].Data)) {
+		return
+	}
+
+	decodedRevisions := make([]DecodedItemRevision, 0, len(revisions))
+
+	for _, revision := range revisions {
+		decodedRevision, err := revision.Decode()
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not decode a revision",
+			})
+			return
+		}
+
+		decodedRevisions = append(decodedRevisions, decodedRevision)
+	}
+
+	c.JSON(200, decodedRevisions)
+}
+
+// getItemRevision fetches a single revision of an item
+func getItemRevision(c *gin.Context) {
+	id, ok := itemIDParam(c)
+	if !ok {
+		return
+	}
+
+	rev, ok := revisionParam(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	revision, err := itemStore.GetRevision(id, rev)
+	if err != nil {
+		respondRevisionError(c, err, "could not fetch revision")
+		return
+	}
+
+	if !checkItemType(c, itemType(revision.Data)) {
+		return
+	}
+
+	decodedRevision, err := revision.Decode()
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode a revision",
+		})
+		return
+	}
+
+	c.JSON(200, decodedRevision)
+}
+
+// getItemRevisionDiff compares a revision with an earlier one, by default the one right before it.
+// The differences are returned as RFC 6902 JSON patch operations.
+func getItemRevisionDiff(c *gin.Context) {
+	id, ok := itemIDParam(c)
+	if !ok {
+		return
+	}
+
+	rev, ok := revisionParam(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	againstRev := rev - 1
+	if s := c.Query("against"); s != "" {
+		if againstRev, ok = revisionParam(c, s); !ok {
+			return
+		}
+	}
+
+	revision, err := itemStore.GetRevision(id, rev)
+	if err != nil {
+		respondRevisionError(c, err, "could not fetch revision")
+		return
+	}
+
+	if !checkItemType(c, itemType(revision.Data)) {
+		return
+	}
+
+	// The first revision is compared against an empty document
+	var before, after interface{}
+
+	if againstRev > 0 {
+		against, err := itemStore.GetRevision(id, againstRev)
+		if err != nil {
+			respondRevisionError(c, err, "could not fetch revision")
+			return
+		}
+
+		if !checkItemType(c, itemType(against.Data)) {
+			return
+		}
+
+		if err = json.Unmarshal(against.Data, &before); err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not decode a revision",
+			})
+			return
+		}
+	} else {
+		before = map[string]interface{}{}
+	}
+
+	if err = json.Unmarshal(revision.Data, &after); err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode a revision",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"from":       againstRev,
+		"to":         rev,
+		"operations": diffJSON(before, after),
+	})
+}
+
+// postItemRevisionRestore brings an item back to the data it had at a revision
+func postItemRevisionRestore(c *gin.Context) {
+	id, ok := itemIDParam(c)
+	if !ok {
+		return
+	}
+
+	rev, ok := revisionParam(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	current, err := liveOrTrashedItem(id)
+	if err != nil {
+		respondRevisionError(c, err, "could not fetch an item")
+		return
+	}
+
+	version, ok := checkIfMatch(c, current)
+	if !ok {
+		return
+	}
+
+	revision, err := itemStore.GetRevision(id, rev)
+	if err != nil {
+		respondRevisionError(c, err, "could not fetch revision")
+		return
+	}
+
+	// Keys must be allowed both the type the item has now and the type it is restored to
+	if !checkItemType(c, itemType(current.Data)) || !checkItemType(c, itemType(revision.Data)) {
+		return
+	}
+
+	// Revisions were saved under the schema of their time, so they are checked again like a new update
+	var data map[string]interface{}
+	if err := json.Unmarshal(revision.Data, &data); err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode revision",
+		})
+		return
+	}
+
+	if err := validateItemData(data); err != nil {
+		c.JSON(422, gin.H{
+			"status":  "error",
+			"message": "revision data is not valid any more",
+			"errors":  err.(*ValidationError).Errors,
+		})
+		return
+	}
+
+	if !checkImagesStored(c, data) {
+		return
+	}
+
+	item, err := itemStore.RestoreRevision(id, rev, version)
+	if err == ErrVersionMismatch {
+		respondVersionMismatch(c, id)
+		return
+	} else if err != nil {
+		respondRevisionError(c, err, "could not restore revision")
+		return
+	}
+
+	decodedItem, err := item.Decode()
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode an item",
+		})
+		return
+	}
+
+	c.Header("ETag", item.ETag())
+	c.JSON(200, gin.H{
+		"status":  "ok",
+		"message": "successfully restored revision",
+		"item":    decodedItem,
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostItemRevisionRestore(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	// A revision saved before locations needed a time zone with their coordinates
+	item, _ := itemStore.Create([]byte(`{"type": "location", "title": "Cafe", "coverImageURL": "abc", "coordinates": [1.284, 103.8514]}`))
+	itemStore.Update(item.ID, []byte(`{"type": "location", "title": "Cafe", "coverImageURL": "abc"}`), 0)
+	itemStore.Update(item.ID, []byte(`{"type": "location", "title": "Coffee Shop", "coverImageURL": "abc"}`), 0)
+
+	w := testRequest(r, "POST", "/item/1/revisions/1/restore", editor, "")
+	assert.Equal(t, 422, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"timeZone"`)
+
+	restore := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/item/1/revisions/2/restore", strings.NewReader(""))
+		req.Header.Set("Authorization", "Bearer "+editor)
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The item was edited since version 2
+	assert.Equal(t, 412, restore(`"1-2"`).Code)
+
+	w = restore(`"1-3"`)
+	if assert.Equal(t, 200, w.Code) {
+		assert.Equal(t, `"1-4"`, w.Header().Get("ETag"))
+	}
+
+	// Items in the trash are restored as well
+	itemStore.Delete(item.ID, 0)
+	assert.Equal(t, 200, restore(`"1-5"`).Code)
+
+	current, _ := itemStore.Get(item.ID)
+	assert.JSONEq(t, `{"type": "location", "title": "Cafe", "coverImageURL": "abc"}`, string(current.Data))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNearbyItems(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	cafe := `{"type": "location", "title": "Cafe", "coverImageURL": "abc", "coordinates": [1.2840, 103.8514], "timeZone": "Asia/Singapore"}`
+	museum := `{"type": "location", "title": "Museum", "coverImageURL": "abc", "coordinates": [1.2966, 103.8485], "timeZone": "Asia/Singapore"}`
+	airport := `{"type": "location", "title": "Airport", "coverImageURL": "abc", "coordinates": [1.3644, 103.9915], "timeZone": "Asia/Singapore"}`
+	for _, body := range []string{cafe, museum, airport} {
+		assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, body).Code)
+	}
+
+	w := testRequest(r, "GET", "/items/nearby?lat=1.2838&lng=103.8591&radius=3000&type=location", editor, "")
+	if !assert.Equal(t, 200, w.Code) {
+		return
+	}
+
+	var response struct {
+		Items []struct {
+			Data     map[string]interface{} `json:"data"`
+			Distance float64                `json:"distance"`
+		} `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if assert.Len(t, response.Items, 2) {
+		assert.Equal(t, "Cafe", response.Items[0].Data["title"])
+		assert.Equal(t, "Museum", response.Items[1].Data["title"])
+		assert.True(t, response.Items[0].Distance < response.Items[1].Distance)
+	}
+
+	assert.Equal(t, 400, testRequest(r, "GET", "/items/nearby?lat=1.2838", editor, "").Code)
+	assert.Equal(t, 400, testRequest(r, "GET", "/items/nearby?lat=1.2838&lng=103.8591&radius=1000000", editor, "").Code)
+	assert.Equal(t, 400, testRequest(r, "GET", "/items/nearby?lat=NaN&lng=103.8591", editor, "").Code)
+	assert.Equal(t, 400, testRequest(r, "GET", "/items/nearby?lat=1.2838&lng=NaN", editor, "").Code)
+	assert.Equal(t, 400, testRequest(r, "GET", "/items/nearby?lat=1.2838&lng=103.8591&radius=NaN", editor, "").Code)
+	assert.Equal(t, 400, testRequest(r, "GET", "/items/nearby?lat=1.2838&lng=103.8591&radius=-Inf", editor, "").Code)
+}
+
+func TestPutItemAsReturnedByGet(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	cafe := `{"type": "location", "title": "Cafe", "coverImageURL": "abc", "openingHours": {"monday": "9-17"}}`
+	w := testRequest(r, "POST", "/item", editor, cafe)
+	if !assert.Equal(t, 201, w.Code) {
+		return
+	}
+	assert.Equal(t, `"1-1"`, w.Header().Get("ETag"))
+
+	var created struct {
+		ID      int64 `json:"id"`
+		Version int64 `json:"version"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, int64(1), created.ID)
+	assert.Equal(t, int64(1), created.Version)
+
+	w = testRequest(r, "GET", "/item/1", editor, "")
+	if !assert.Equal(t, 200, w.Code) {
+		return
+	}
+	assert.Contains(t, w.Body.String(), `"title":"Cafe"`)
+
+	// The read-only fields sent back are ignored
+	assert.Equal(t, 200, testRequest(r, "PUT", "/item/1", editor, w.Body.String()).Code)
+
+	item, err := itemStore.Get(1)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(item.Data), "createdAt")
+	}
+}
+
+func TestItemUnknownImage(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	w := testRequest(r, "POST", "/item", editor, `{"type": "location", "title": "Cafe", "coverImageURL": "abd", "imageURLs": ["abc", "gone"]}`)
+	assert.Equal(t, 422, w.Code)
+	assert.Contains(t, w.Body.String(), `{"field":"coverImageURL","message":"no image is stored with the ID \"abd\""}`)
+	assert.Contains(t, w.Body.String(), `{"field":"imageURLs[1]","message":"no image is stored with the ID \"gone\""}`)
+
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, `{"type": "location", "title": "Cafe", "coverImageURL": "abc"}`).Code)
+	assert.Equal(t, 422, testRequest(r, "PUT", "/item/1", editor, `{"type": "location", "title": "Cafe", "coverImageURL": "abd"}`).Code)
+
+	req := httptest.NewRequest("PATCH", "/item/1", strings.NewReader(`{"coverImageURL": "abd"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("Authorization", "Bearer "+editor)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 422, w.Code)
+}
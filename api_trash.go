@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// getTrash lists the items that have been deleted but not purged yet
+func getTrash(c *gin.Context) {
+	items, err := itemStore.ListTrash()
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not fetch items",
+		})
+		return
+	}
+
+	key, scoped := requestAPIKey(c)
+	decodedItems := make([]DecodedItem, 0, len(items))
+
+	for _, item := range items {
+		if scoped && !key.AllowsType(itemType(item.Data)) {
+			continue
+		}
+
+		decodedItem, err := item.Decode()
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not decode an item",
+			})
+			return
+		}
+
+		decodedItems = append(decodedItems, decodedItem)
+	}
+
+	c.JSON(200, decodedItems)
+}
+
+// postItemRestore takes an item out of the trash
+func postItemRestore(c *gin.Context) {
+	id, ok := itemIDParam(c)
+	if !ok {
+		return
+	}
+
+	// Keys may only restore items of the types they are scoped to
+	if key, ok := requestAPIKey(c); ok && len(key.ItemTypes) > 0 {
+		items, err := itemStore.ListTrash()
+		if err != nil {
+			log.Error(err)
+			c.JSON(500, gin.H{
+				"status":  "error",
+				"message": "could not fetch items",
+			})
+			return
+		}
+
+		for _, item := range items {
+			if item.ID == id && !checkItemType(c, itemType(item.Data)) {
+				return
+			}
+		}
+	}
+
+	item, err := itemStore.Restore(id)
+	if err == ErrItemNotFound {
+		c.JSON(404, gin.H{
+			"status":  "error",
+			"message": "item is not in the trash",
+		})
+		return
+	} else if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not restore item",
+		})
+		return
+	}
+
+	decodedItem, err := item.Decode()
+	if err != nil {
+		log.Error(err)
+		c.JSON(500, gin.H{
+			"status":  "error",
+			"message": "could not decode an item",
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":  "ok",
+		"message": "successfully restored item",
+		"item":    decodedItem,
+	})
+}
+
+// purgeCommand permanently removes items that have been in the trash for longer than the retention period
+func purgeCommand(c *cli.Context) error {
+	db, err := openPostgres(dbConnStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	store := NewPostgresItemStore(db)
+
+	retention := c.Duration("retention")
+	if retention < 0 {
+		return fmt.Errorf("retention must not be negative")
+	}
+
+	items, err := store.Purge(time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		log.Infof("Purged item %d deleted at %s", item.ID, item.DeletedAt.Format(time.RFC3339))
+	}
+
+	fmt.Printf("Purged %d item(s)\n", len(items))
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// openAPIKeyStore opens the Postgres API key store for the apikey commands
+func openAPIKeyStore() (store *PostgresAPIKeyStore, close func() error, err error) {
+	db, err := openPostgres(dbConnStr)
+	if err != nil {
+		return
+	}
+
+	return NewPostgresAPIKeyStore(db), db.Close, nil
+}
+
+// splitList splits the values of a string slice flag, which may also be separated by commas
+func splitList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+func apiKeyCreateCommand(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("API key name is required")
+	}
+
+	key, token, err := newAPIKey(name, splitList(c.StringSlice("types")), splitList(c.StringSlice("actions")), c.Duration("expires"))
+	if err != nil {
+		return err
+	}
+
+	store, close, err := openAPIKeyStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	if key, err = store.CreateAPIKey(key); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key \"%s\" with prefix %s\n", key.Name, key.Prefix)
+	fmt.Println("Store it somewhere safe now, it won't be shown again:")
+	fmt.Println(token)
+	return nil
+}
+
+func apiKeyListCommand(c *cli.Context) error {
+	store, close, err := openAPIKeyStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	keys, err := store.ListAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PREFIX\tNAME\tTYPES\tACTIONS\tEXPIRES AT\tLAST USED AT\tSTATUS")
+
+	for _, key := range keys {
+		types := "all"
+		if len(key.ItemTypes) > 0 {
+			types = strings.Join(key.ItemTypes, ",")
+		}
+
+		status := "active"
+		if key.RevokedAt != nil {
+			status = "revoked"
+		} else if !key.Active(time.Now()) {
+			status = "expired"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.Prefix, key.Name, types, strings.Join(key.Actions, ","),
+			formatTime(key.ExpiresAt), formatTime(key.LastUsedAt), status)
+	}
+
+	return w.Flush()
+}
+
+func apiKeyRevokeCommand(c *cli.Context) error {
+	prefix := c.Args().First()
+	if prefix == "" {
+		return errors.New("API key prefix is required")
+	}
+
+	store, close, err := openAPIKeyStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	if err = store.RevokeAPIKey(prefix); err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked API key %s\n", prefix)
+	return nil
+}
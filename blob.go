@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when the requested blob does not exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key         string            `json:"key"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"contentType"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	ModTime     time.Time         `json:"modTime"`
+}
+
+// BlobStore is the storage backend for uploaded images and other files.
+// Keys are slash-separated paths such as "<id>" or "derivatives/<id>/640".
+type BlobStore interface {
+	// Put stores the content of r under info.Key, replacing any existing blob.
+	// info.Size must be the length of the content; ModTime is set by the store.
+	Put(info BlobInfo, r io.Reader) error
+
+	// Get opens a blob for reading. The caller must close it.
+	Get(key string) (io.ReadCloser, BlobInfo, error)
+
+	// Stat fetches the description of a blob without its content
+	Stat(key string) (BlobInfo, error)
+
+	// Delete removes a blob. Deleting a blob that doesn't exist is not an error.
+	Delete(key string) error
+
+	// List describes all blobs whose key starts with prefix, ordered by key.
+	// Stores may leave out the content type and metadata.
+	List(prefix string) ([]BlobInfo, error)
+}
+
+// checkBlobKey makes sure that a key can't escape the root of a store.
+// Names starting with a dot are reserved for the stores themselves.
+func checkBlobKey(key string) error {
+	if key == "" || path.Clean(key) != key || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("\"%s\" is not a valid blob key", key)
+	}
+
+	for _, name := range strings.Split(key, "/") {
+		if strings.HasPrefix(name, ".") {
+			return fmt.Errorf("\"%s\" is not a valid blob key", key)
+		}
+	}
+
+	return nil
+}
+
+// readBlob reads the whole content of a blob
+func readBlob(store BlobStore, key string) ([]byte, error) {
+	r, _, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// blobStoreFlags are the flags of the commands that need a BlobStore
+var blobStoreFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "blob-store",
+		Value: "fs",
+		Usage: "set where images are stored (fs or s3)",
+	},
+	&cli.StringFlag{
+		Name:  "blob-path",
+		Value: "files",
+		Usage: "set the directory of the fs blob store",
+	},
+	&cli.StringFlag{
+		Name:  "s3-endpoint",
+		Usage: "set the URL of the S3-compatible service, such as https://s3.eu-west-1.amazonaws.com",
+	},
+	&cli.StringFlag{
+		Name:  "s3-bucket",
+		Usage: "set the bucket of the s3 blob store",
+	},
+	&cli.StringFlag{
+		Name:  "s3-region",
+		Value: "us-east-1",
+		Usage: "set the region of the s3 blob store",
+	},
+	&cli.StringFlag{
+		Name:    "s3-access-key-id",
+		EnvVars: []string{"S3_ACCESS_KEY_ID"},
+		Usage:   "set the access key ID of the s3 blob store",
+	},
+	&cli.StringFlag{
+		Name:    "s3-secret-access-key",
+		EnvVars: []string{"S3_SECRET_ACCESS_KEY"},
+		Usage:   "set the secret access key of the s3 blob store",
+	},
+}
+
+// openBlobStore sets blobStore according to the blobStoreFlags of a command
+func openBlobStore(c *cli.Context) error {
+	switch c.String("blob-store") {
+	case "fs":
+		blobStore = NewFSBlobStore(c.String("blob-path"))
+	case "s3":
+		if c.String("s3-endpoint") == "" || c.String("s3-bucket") == "" {
+			return errors.New("s3 blob store requires --s3-endpoint and --s3-bucket")
+		}
+
+		blobStore = NewS3BlobStore(S3Config{
+			Endpoint:        c.String("s3-endpoint"),
+			Bucket:          c.String("s3-bucket"),
+			Region:          c.String("s3-region"),
+			AccessKeyID:     c.String("s3-access-key-id"),
+			SecretAccessKey: c.String("s3-secret-access-key"),
+		})
+	default:
+		return fmt.Errorf("unknown blob store \"%s\", must be fs or s3", c.String("blob-store"))
+	}
+
+	return nil
+}
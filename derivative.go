@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "image/gif"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// derivativeWidths are the widths that images are resized to for responsive pages.
+// Images are never scaled up, so smaller images get fewer variants.
+var derivativeWidths = []int{320, 640, 1280}
+
+// thumbnailSize is the width and height of the square thumbnail of an image
+const thumbnailSize = 160
+
+// thumbnailName is the name of the thumbnail among the derivatives of an image
+const thumbnailName = "thumb"
+
+// ZolaImage is an image published with a page, along with its resized variants.
+// Srcset can be used as it is in the srcset attribute of an img element.
+type ZolaImage struct {
+	URL           string             `toml:"url"`
+	Width         int                `toml:"width"`
+	Height        int                `toml:"height"`
+	Size          int64              `toml:"size"`
+	DominantColor string             `toml:"dominant_color"`
+	Blurhash      string             `toml:"blurhash"`
+	ThumbnailURL  string             `toml:"thumbnail_url"`
+	Variants      []ZolaImageVariant `toml:"variants"`
+	Srcset        string             `toml:"srcset"`
+}
+
+// ZolaImageVariant is a resized copy of a ZolaImage
+type ZolaImageVariant struct {
+	URL    string `toml:"url"`
+	Width  int    `toml:"width"`
+	Height int    `toml:"height"`
+}
+
+// derivativeKey returns the blob key of a derivative of the image stored under key.
+// Image keys are content hashes, so derivatives never go stale.
+func derivativeKey(key, name string) string {
+	return "derivatives/" + key + "/" + name
+}
+
+// derivativeExtension returns the extension of the derivatives of an image.
+// Formats that can be transparent are resized to PNG, and everything else to JPEG.
+func derivativeExtension(contentType string) string {
+	switch contentType {
+	case "image/png", "image/gif", "image/webp":
+		return ".png"
+	default:
+		return ".jpg"
+	}
+}
+
+// encodeDerivative encodes img in the format given by ext
+func encodeDerivative(img image.Image, ext string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var err error
+	if ext == ".png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80})
+	}
+
+	return buf.Bytes(), err
+}
+
+// resizeImage scales img to fit width and height. When crop is set, the image is
+// cropped around its center to the aspect ratio of width and height first.
+func resizeImage(img image.Image, width, height int, crop bool) image.Image {
+	src := img.Bounds()
+
+	if crop {
+		srcWidth, srcHeight := src.Dx(), src.Dy()
+		if srcWidth*height > srcHeight*width {
+			w := srcHeight * width / height
+			src.Min.X += (srcWidth - w) / 2
+			src.Max.X = src.Min.X + w
+		} else {
+			h := srcWidth * height / width
+			src.Min.Y += (srcHeight - h) / 2
+			src.Max.Y = src.Min.Y + h
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+	return dst
+}
+
+// putDerivative resizes img and stores the result as a derivative of the image stored under key
+func putDerivative(key, name string, img image.Image, width, height int, crop bool, ext string) error {
+	data, err := encodeDerivative(resizeImage(img, width, height, crop), ext)
+	if err != nil {
+		return err
+	}
+
+	contentType := "image/jpeg"
+	if ext == ".png" {
+		contentType = "image/png"
+	}
+
+	return blobStore.Put(BlobInfo{
+		Key:         derivativeKey(key, name),
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		Metadata: map[string]string{
+			"width":  strconv.Itoa(width),
+			"height": strconv.Itoa(height),
+		},
+	}, bytes.NewReader(data))
+}
+
+// ensureDerivatives creates the derivatives of the image stored under key, unless they exist already.
+// data and contentType are those of the original image. It returns the size of the original image
+// and the stored derivatives.
+func ensureDerivatives(key string, data []byte, contentType string) (width, height int, derivatives []BlobInfo, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	width, height = config.Width, config.Height
+
+	if derivatives, err = listDerivatives(key); err != nil || len(derivatives) > 0 {
+		return
+	}
+
+	if err = checkImageDimensions(config); err != nil {
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	ext := derivativeExtension(contentType)
+
+	for _, w := range derivativeWidths {
+		if w >= width {
+			break
+		}
+
+		h := height * w / width
+		if h < 1 {
+			h = 1
+		}
+
+		if err = putDerivative(key, strconv.Itoa(w), img, w, h, false, ext); err != nil {
+			return
+		}
+	}
+
+	if err = putDerivative(key, thumbnailName, img, thumbnailSize, thumbnailSize, true, ext); err != nil {
+		return
+	}
+
+	derivatives, err = listDerivatives(key)
+	return
+}
+
+// listDerivatives describes the stored derivatives of an image, including their metadata
+func listDerivatives(key string) (derivatives []BlobInfo, err error) {
+	infos, err := blobStore.List(derivativeKey(key, ""))
+	if err != nil {
+		return
+	}
+
+	for _, info := range infos {
+		// Some stores don't list metadata
+		if info, err = blobStore.Stat(info.Key); err != nil {
+			return
+		}
+
+		derivatives = append(derivatives, info)
+	}
+
+	return
+}
+
+// writeStaticFile writes data to the Zola static directory at the URL path urlPath
+func writeStaticFile(urlPath string, data []byte) error {
+	filename := filepath.Join(zolaPath, "static", filepath.FromSlash(urlPath))
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+// publishImage copies the image stored under key and its derivatives to the Zola static directory.
+// originalURL is the URL of the original image on the site; the derivatives are put next to it.
+func publishImage(key, originalURL string) (zolaImage ZolaImage, err error) {
+	zolaImage.URL = originalURL
+
+	data, err := readBlob(blobStore, key)
+	if err != nil {
+		return
+	}
+
+	if err = writeStaticFile(originalURL, data); err != nil {
+		return
+	}
+
+	meta, err := loadImageMeta(key)
+	if err != nil {
+		return
+	}
+
+	zolaImage.Size = meta.Size
+	zolaImage.DominantColor = meta.DominantColor
+	zolaImage.Blurhash = meta.Blurhash
+
+	// Vector images look sharp at every size
+	if meta.ContentType == "image/svg+xml" {
+		zolaImage.ThumbnailURL = originalURL
+		return
+	}
+
+	width, height, derivatives, err := ensureDerivatives(key, data, meta.ContentType)
+	if err != nil {
+		return
+	}
+
+	zolaImage.Width = width
+	zolaImage.Height = height
+
+	base := strings.TrimSuffix(originalURL, filepath.Ext(originalURL))
+
+	for _, derivative := range derivatives {
+		name := derivative.Key[strings.LastIndex(derivative.Key, "/")+1:]
+		url := base + "-" + name + imageExtensions[derivative.ContentType]
+
+		if data, err = readBlob(blobStore, derivative.Key); err != nil {
+			return
+		}
+
+		if err = writeStaticFile(url, data); err != nil {
+			return
+		}
+
+		if name == thumbnailName {
+			zolaImage.ThumbnailURL = url
+			continue
+		}
+
+		w, _ := strconv.Atoi(derivative.Metadata["width"])
+		h, _ := strconv.Atoi(derivative.Metadata["height"])
+		zolaImage.Variants = append(zolaImage.Variants, ZolaImageVariant{URL: url, Width: w, Height: h})
+	}
+
+	// The original is the largest variant
+	zolaImage.Variants = append(zolaImage.Variants, ZolaImageVariant{URL: originalURL, Width: width, Height: height})
+	sort.Slice(zolaImage.Variants, func(i, j int) bool { return zolaImage.Variants[i].Width < zolaImage.Variants[j].Width })
+
+	var srcset []string
+	for _, variant := range zolaImage.Variants {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", variant.URL, variant.Width))
+	}
+	zolaImage.Srcset = strings.Join(srcset, ", ")
+
+	return
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testJPEG encodes a blank JPEG image of the given size
+func testJPEG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGenerateLocationContentDerivatives(t *testing.T) {
+	itemStore = NewMemoryItemStore()
+	blobStore = NewFSBlobStore(t.TempDir())
+	zolaPath = t.TempDir()
+	defer func() { zolaPath = "" }()
+
+	os.MkdirAll(filepath.Join(zolaPath, "content", "locations"), 0700)
+
+	photo := testJPEG(t, 800, 600)
+	blobStore.Put(BlobInfo{Key: "photo", Size: int64(len(photo)), ContentType: "image/jpeg"}, bytes.NewReader(photo))
+
+	location := Location{ID: 3, Type: "location", Title: "Cafe", CoverImageURL: "photo", ImageURLs: []string{"photo"}}
+	assert.Nil(t, generateLocationContent(location, time.Now()))
+
+	for _, name := range []string{"photo.jpg", "photo-320.jpg", "photo-640.jpg", "photo-thumb.jpg"} {
+		_, err := os.Stat(filepath.Join(zolaPath, "static", "img", "cover", "location", name))
+		assert.Nil(t, err, name)
+	}
+
+	_, err := os.Stat(filepath.Join(zolaPath, "static", "img", "cover", "location", "photo-1280.jpg"))
+	assert.True(t, os.IsNotExist(err), "images are not scaled up")
+
+	page, _ := os.ReadFile(filepath.Join(zolaPath, "content", "locations", "3.md"))
+	assert.Contains(t, string(page), `srcset = "/img/cover/location/photo-320.jpg 320w, /img/cover/location/photo-640.jpg 640w, /img/cover/location/photo.jpg 800w"`)
+	assert.Contains(t, string(page), `thumbnail_url = "/img/location/3/photo-thumb.jpg"`)
+
+	thumb, err := blobStore.Stat(derivativeKey("photo", thumbnailName))
+	assert.Nil(t, err)
+	assert.Equal(t, "160", thumb.Metadata["width"])
+
+	// Derivatives are cached by content hash, so publishing again reuses them
+	assert.Nil(t, generateLocationContent(location, time.Now()))
+
+	cached, _ := blobStore.Stat(derivativeKey("photo", thumbnailName))
+	assert.Equal(t, thumb.ModTime, cached.ModTime)
+
+	// Published files are removed even when the image is gone from the blob store
+	assert.Nil(t, blobStore.Delete("photo"))
+	assert.Nil(t, removeGeneratedContent(Item{ID: 3, Data: []byte(`{"type": "location", "coverImageURL": "photo"}`)}))
+
+	files, _ := filepath.Glob(filepath.Join(zolaPath, "static", "img", "cover", "location", "*"))
+	assert.Empty(t, files)
+}
+
+func TestDerivativeExtension(t *testing.T) {
+	assert.Equal(t, ".jpg", derivativeExtension("image/jpeg"))
+	assert.Equal(t, ".png", derivativeExtension("image/png"))
+	assert.Equal(t, ".png", derivativeExtension("image/gif"))
+	assert.Equal(t, ".png", derivativeExtension("image/webp"))
+}
+
+func TestEnsureDerivativesPixelLimit(t *testing.T) {
+	blobStore = NewFSBlobStore(t.TempDir())
+
+	maxImagePixels = 100
+	defer func() { maxImagePixels = 50000000 }()
+
+	_, _, _, err := ensureDerivatives("large", testJPEG(t, 20, 10), "image/jpeg")
+	assert.Equal(t, errImageDimensions, err)
+}
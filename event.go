@@ -14,14 +14,30 @@ type ZolaEvent struct {
 		Tags []string `toml:"tags"`
 	} `toml:"taxonomies"`
 	Extra struct {
-		Type          string    `toml:"type"`
-		Description   string    `toml:"-"`
-		Address       string    `toml:"address"`
-		Coordinates   []float64 `toml:"coordinates"`
-		Phone         string    `toml:"phone"`
-		WebsiteURL    string    `toml:"website_url"`
-		CoverImageURL string    `toml:"cover_image_url"`
-		ImageURLs     []string  `toml:"image_urls"`
+		Type          string      `toml:"type"`
+		Description   string      `toml:"-"`
+		Address       string      `toml:"address"`
+		Coordinates   []float64   `toml:"coordinates"`
+		Phone         string      `toml:"phone"`
+		WebsiteURL    string      `toml:"website_url"`
+		CoverImageURL string      `toml:"cover_image_url"`
+		ImageURLs     []string    `toml:"image_urls"`
+		CoverImage    ZolaImage   `toml:"cover_image"`
+		Images        []ZolaImage `toml:"images"`
+
+		// The schedule, with the first start and the last end of all sessions.
+		// Times are RFC 3339 in the time zone of the event, and dates are for grouping.
+		StartsAt  string             `toml:"starts_at,omitempty"`
+		EndsAt    string             `toml:"ends_at,omitempty"`
+		StartDate string             `toml:"start_date,omitempty"`
+		EndDate   string             `toml:"end_date,omitempty"`
+		TimeZone  string             `toml:"time_zone,omitempty"`
+		AllDay    bool               `toml:"all_day"`
+		Sessions  []ZolaEventSession `toml:"sessions"`
+
+		// The recurrence rule of a series and its upcoming occurrences
+		RRule       string             `toml:"rrule,omitempty"`
+		Occurrences []ZolaEventSession `toml:"occurrences"`
 	} `toml:"extra"`
 	CreatedAt time.Time `toml:"date"`
 	UpdatedAt time.Time `toml:"updated_at"`
@@ -31,19 +47,31 @@ type ZolaEvent struct {
 // For example, the event name, address, schedule, phone number,
 // website URL, etc..
 type Event struct {
-	ID            int64     `toml:"id"`
-	Type          string    `toml:"type"`
-	Title         string    `toml:"title"`
-	Description   string    `toml:"description"`
-	Address       string    `toml:"address"`
-	Coordinates   []float64 `toml:"coordinates"`
-	Phone         string    `toml:"phone"`
+	ID            int64     `toml:"id" json:"id"`
+	Type          string    `toml:"type" json:"type"`
+	Title         string    `toml:"title" json:"title"`
+	Description   string    `toml:"description" json:"description"`
+	Address       string    `toml:"address" json:"address"`
+	Coordinates   []float64 `toml:"coordinates" json:"coordinates"`
+	Phone         string    `toml:"phone" json:"phone"`
 	WebsiteURL    string    `toml:"website_url" json:"websiteURL"`
 	CoverImageURL string    `toml:"cover_image_url" json:"coverImageURL"`
 	ImageURLs     []string  `toml:"image_urls" json:"imageURLs"`
-	Tags          []string  `toml:"tags"`
-	CreatedAt     time.Time `toml:"created_at"`
-	UpdatedAt     time.Time `toml:"updated_at"`
+	Tags          []string  `toml:"tags" json:"tags"`
+	CreatedAt     time.Time `toml:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time `toml:"updated_at" json:"updatedAt"`
+
+	// Wall-clock times in TimeZone, or dates when AllDay is set
+	StartsAt string         `toml:"starts_at" json:"startsAt,omitempty"`
+	EndsAt   string         `toml:"ends_at" json:"endsAt,omitempty"`
+	TimeZone string         `toml:"time_zone" json:"timeZone,omitempty"`
+	AllDay   bool           `toml:"all_day" json:"allDay,omitempty"`
+	Sessions []EventSession `toml:"sessions" json:"sessions,omitempty"`
+
+	// An RFC 5545 recurrence rule, and extra or excluded starts in the same format as StartsAt
+	RRule   string   `toml:"rrule" json:"rrule,omitempty"`
+	ExDates []string `toml:"exdates" json:"exDates,omitempty"`
+	RDates  []string `toml:"rdates" json:"rDates,omitempty"`
 }
 
 // EventFromData converts a JSONB byte-array into an Event structure
@@ -78,9 +106,55 @@ func (event *Event) Zola() (zolaEvent ZolaEvent, err error) {
 	zolaEvent.Extra.WebsiteURL = event.WebsiteURL
 
 	// Append base path for images to be loaded by Zola
-	zolaEvent.Extra.CoverImageURL = fmt.Sprintf("/img/cover/event/%s.jpg", event.CoverImageURL)
+	ext, err := blobImageExtension(event.CoverImageURL)
+	if err != nil {
+		return
+	}
+	zolaEvent.Extra.CoverImageURL = fmt.Sprintf("/img/cover/event/%s%s", event.CoverImageURL, ext)
 	for _, imageURL := range event.ImageURLs {
-		zolaEvent.Extra.ImageURLs = append(zolaEvent.Extra.ImageURLs, fmt.Sprintf("/img/event/%d/%s.jpg", event.ID, imageURL))
+		if ext, err = blobImageExtension(imageURL); err != nil {
+			return
+		}
+		zolaEvent.Extra.ImageURLs = append(zolaEvent.Extra.ImageURLs, fmt.Sprintf("/img/event/%d/%s%s", event.ID, imageURL, ext))
+	}
+
+	periods, err := event.Periods()
+	if err != nil {
+		return
+	}
+
+	var lastEnd time.Time
+	for i, period := range periods {
+		session := zolaEventSession(period, event.AllDay)
+		if i == 0 {
+			zolaEvent.Extra.StartsAt = session.StartsAt
+			zolaEvent.Extra.StartDate = session.StartDate
+			zolaEvent.Extra.TimeZone = event.TimeZone
+			zolaEvent.Extra.AllDay = event.AllDay
+		}
+
+		if i == 0 || period.End.After(lastEnd) {
+			lastEnd = period.End
+			zolaEvent.Extra.EndsAt = session.EndsAt
+			zolaEvent.Extra.EndDate = session.EndDate
+		}
+
+		if len(event.Sessions) > 0 {
+			zolaEvent.Extra.Sessions = append(zolaEvent.Extra.Sessions, session)
+		}
+	}
+
+	if event.RRule != "" {
+		var loc *time.Location
+		if loc, err = event.Location(); err != nil {
+			return
+		}
+
+		var rule RRule
+		if rule, err = parseRRule(event.RRule, loc); err != nil {
+			return
+		}
+		zolaEvent.Extra.RRule = rule.String()
 	}
 
 	zolaEvent.Taxonomies.Tags = event.Tags
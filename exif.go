@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// ImageMetadata is what was found in the embedded metadata (EXIF and XMP) of an image
+type ImageMetadata struct {
+	Orientation int  // EXIF orientation from 1 to 8, or 0 when there is none
+	HasLocation bool // whether the metadata includes GPS coordinates
+	HasMetadata bool // whether there is any EXIF or XMP metadata at all
+}
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// parseExif reads the orientation and looks for GPS data in an EXIF block,
+// which is laid out like a TIFF file
+func parseExif(tiff []byte, metadata *ImageMetadata) {
+	tiff = bytes.TrimPrefix(tiff, exifHeader)
+	metadata.HasMetadata = true
+
+	if len(tiff) < 8 {
+		return
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) || ifd < 8 {
+		return
+	}
+
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return
+		}
+
+		switch order.Uint16(tiff[entry:]) {
+		case 0x0112: // Orientation
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				metadata.Orientation = orientation
+			}
+		case 0x8825: // GPSInfo
+			gps := int(order.Uint32(tiff[entry+8:]))
+			if gps >= 8 && gps+2 <= len(tiff) && order.Uint16(tiff[gps:]) > 0 {
+				metadata.HasLocation = true
+			}
+		}
+	}
+}
+
+// parseXMP looks for GPS data in an XMP packet
+func parseXMP(xmp []byte, metadata *ImageMetadata) {
+	metadata.HasMetadata = true
+
+	if bytes.Contains(xmp, []byte("GPSLatitude")) || bytes.Contains(xmp, []byte("GPSLongitude")) {
+		metadata.HasLocation = true
+	}
+}
+
+// jpegSegment is a marker segment of a JPEG file before its image data
+type jpegSegment struct {
+	marker  byte
+	start   int // offset of the 0xFF byte of the marker
+	end     int
+	payload []byte
+}
+
+// jpegSegments splits the header of a JPEG file into its marker segments.
+// It returns the offset where the image data starts.
+func jpegSegments(data []byte) (segments []jpegSegment, scanStart int, err error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		err = errImageType
+		return
+	}
+
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			err = errImageType
+			return
+		}
+
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			err = errImageType
+			return
+		}
+
+		// The image data follows the start of scan segment
+		if marker == 0xDA {
+			scanStart = i
+			return
+		}
+
+		segments = append(segments, jpegSegment{
+			marker:  marker,
+			start:   i,
+			end:     i + 2 + length,
+			payload: data[i+4 : i+2+length],
+		})
+		i += 2 + length
+	}
+}
+
+// pngChunk is a chunk of a PNG file
+type pngChunk struct {
+	typ   string
+	start int
+	end   int
+	data  []byte
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunks splits a PNG file into its chunks
+func pngChunks(data []byte) (chunks []pngChunk, err error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		err = errImageType
+		return
+	}
+
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			err = errImageType
+			return
+		}
+
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		if length < 0 || i+12+length > len(data) {
+			err = errImageType
+			return
+		}
+
+		chunks = append(chunks, pngChunk{
+			typ:   string(data[i+4 : i+8]),
+			start: i,
+			end:   i + 12 + length,
+			data:  data[i+8 : i+8+length],
+		})
+		i += 12 + length
+	}
+
+	return
+}
+
+// webpChunk is a chunk of a WebP file
+type webpChunk struct {
+	fourCC string
+	start  int
+	end    int
+	data   []byte
+}
+
+// webpChunks splits a WebP file into its chunks
+func webpChunks(data []byte) (chunks []webpChunk, err error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		err = errImageType
+		return
+	}
+
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			err = errImageType
+			return
+		}
+
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2 // chunks are padded to an even size
+		if size < 0 || i+8+size > len(data) {
+			err = errImageType
+			return
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+
+		chunks = append(chunks, webpChunk{
+			fourCC: string(data[i : i+4]),
+			start:  i,
+			end:    end,
+			data:   data[i+8 : i+8+size],
+		})
+		i = end
+	}
+
+	return
+}
+
+// readImageMetadata looks for EXIF and XMP metadata in an image
+func readImageMetadata(data []byte, contentType string) (metadata ImageMetadata, err error) {
+	switch contentType {
+	case "image/jpeg":
+		var segments []jpegSegment
+		if segments, _, err = jpegSegments(data); err != nil {
+			return
+		}
+
+		for _, segment := range segments {
+			if segment.marker == 0xE1 && bytes.HasPrefix(segment.payload, exifHeader) {
+				parseExif(segment.payload, &metadata)
+			} else if segment.marker == 0xE1 && bytes.HasPrefix(segment.payload, xmpHeader) {
+				parseXMP(segment.payload, &metadata)
+			}
+		}
+	case "image/png":
+		var chunks []pngChunk
+		if chunks, err = pngChunks(data); err != nil {
+			return
+		}
+
+		for _, chunk := range chunks {
+			switch chunk.typ {
+			case "eXIf":
+				parseExif(chunk.data, &metadata)
+			case "tEXt", "zTXt", "iTXt":
+				parseXMP(chunk.data, &metadata)
+			}
+		}
+	case "image/webp":
+		var chunks []webpChunk
+		if chunks, err = webpChunks(data); err != nil {
+			return
+		}
+
+		for _, chunk := range chunks {
+			switch chunk.fourCC {
+			case "EXIF":
+				parseExif(chunk.data, &metadata)
+			case "XMP ":
+				parseXMP(chunk.data, &metadata)
+			}
+		}
+	}
+
+	return
+}
+
+// stripImageMetadata removes EXIF and XMP metadata from an image.
+// When the metadata rotates or mirrors the image, the pixels are turned the right way
+// and the image is encoded again, in which case the content type may change.
+func stripImageMetadata(data []byte, contentType string) ([]byte, string, error) {
+	metadata, err := readImageMetadata(data, contentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !metadata.HasMetadata {
+		return data, contentType, nil
+	}
+
+	if metadata.Orientation > 1 {
+		return reencodeOriented(data, contentType, metadata.Orientation)
+	}
+
+	var out bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		segments, scanStart, _ := jpegSegments(data)
+
+		out.Write(data[:2])
+		for _, segment := range segments {
+			// APP1 holds EXIF and XMP, and APP13 holds Photoshop and IPTC data
+			if segment.marker == 0xE1 || segment.marker == 0xED {
+				continue
+			}
+			out.Write(data[segment.start:segment.end])
+		}
+		out.Write(data[scanStart:])
+	case "image/png":
+		chunks, _ := pngChunks(data)
+
+		out.Write(pngSignature)
+		for _, chunk := range chunks {
+			switch chunk.typ {
+			case "eXIf", "tEXt", "zTXt", "iTXt":
+				continue
+			}
+			out.Write(data[chunk.start:chunk.end])
+		}
+	case "image/webp":
+		chunks, _ := webpChunks(data)
+
+		out.Write(data[:12])
+		for _, chunk := range chunks {
+			switch chunk.fourCC {
+			case "EXIF", "XMP ":
+				continue
+			case "VP8X":
+				// Clear the flags that announce EXIF and XMP chunks
+				vp8x := append([]byte{}, data[chunk.start:chunk.end]...)
+				if len(vp8x) > 8 {
+					vp8x[8] &^= 0x08 | 0x04
+				}
+				out.Write(vp8x)
+				continue
+			}
+			out.Write(data[chunk.start:chunk.end])
+		}
+
+		stripped := out.Bytes()
+		binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	default:
+		return data, contentType, nil
+	}
+
+	return out.Bytes(), contentType, nil
+}
+
+// reencodeOriented decodes an image, applies an EXIF orientation to it and encodes it again.
+// WebP images are encoded as PNG, since there is no WebP encoder.
+func reencodeOriented(data []byte, contentType string, orientation int) ([]byte, string, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img = orientImage(img, orientation)
+
+	var out bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+	} else {
+		contentType = "image/png"
+		err = png.Encode(&out, img)
+	}
+
+	return out.Bytes(), contentType, err
+}
+
+// orientImage turns an image the way an EXIF orientation says it should be displayed
+func orientImage(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		dstWidth, dstHeight = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counterclockwise
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testExif builds a little-endian EXIF block with an orientation and, optionally, GPS data
+func testExif(orientation uint16, gps bool) []byte {
+	var b bytes.Buffer
+	b.Write(exifHeader)
+	b.WriteString("II*\x00")
+	binary.Write(&b, binary.LittleEndian, uint32(8))
+
+	entries := uint16(1)
+	if gps {
+		entries = 2
+	}
+	binary.Write(&b, binary.LittleEndian, entries)
+	binary.Write(&b, binary.LittleEndian, []uint16{0x0112, 3, 1, 0, orientation, 0})
+	if gps {
+		binary.Write(&b, binary.LittleEndian, []uint16{0x8825, 4, 1, 0})
+		binary.Write(&b, binary.LittleEndian, uint32(8+2+24+4))
+	}
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+
+	if gps {
+		// A GPS IFD with only the latitude reference
+		binary.Write(&b, binary.LittleEndian, uint16(1))
+		binary.Write(&b, binary.LittleEndian, []uint16{0x0001, 2, 2, 0})
+		b.WriteString("N\x00\x00\x00")
+		binary.Write(&b, binary.LittleEndian, uint32(0))
+	}
+
+	return b.Bytes()
+}
+
+// testJPEGWithExif encodes a 4x2 JPEG image whose left half is red and inserts an EXIF block
+func testJPEGWithExif(t *testing.T, exif []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(exif)))
+	segment = append(segment, exif...)
+
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+// testPNGChunk encodes a PNG chunk
+func testPNGChunk(typ string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], typ)
+	chunk = append(chunk, data...)
+
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	return append(chunk, crc...)
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	data := testJPEGWithExif(t, testExif(1, true))
+
+	metadata, err := readImageMetadata(data, "image/jpeg")
+	assert.Nil(t, err)
+	assert.Equal(t, ImageMetadata{Orientation: 1, HasLocation: true, HasMetadata: true}, metadata)
+
+	stripped, contentType, err := stripImageMetadata(data, "image/jpeg")
+	assert.Nil(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+	assert.Equal(t, len(data)-4-len(testExif(1, true)), len(stripped), "only the EXIF segment is removed")
+
+	metadata, _ = readImageMetadata(stripped, "image/jpeg")
+	assert.False(t, metadata.HasMetadata)
+
+	_, err = jpeg.Decode(bytes.NewReader(stripped))
+	assert.Nil(t, err)
+}
+
+func TestStripJPEGMetadataAppliesOrientation(t *testing.T) {
+	// Orientation 6 means the camera was turned, and the image must be rotated clockwise
+	stripped, _, err := stripImageMetadata(testJPEGWithExif(t, testExif(6, true)), "image/jpeg")
+	assert.Nil(t, err)
+
+	metadata, _ := readImageMetadata(stripped, "image/jpeg")
+	assert.False(t, metadata.HasLocation)
+
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, image.Rect(0, 0, 2, 4), img.Bounds())
+
+	// The red left half is now on top
+	r, _, b, _ := img.At(1, 0).RGBA()
+	assert.True(t, r > b)
+	r, _, b, _ = img.At(1, 3).RGBA()
+	assert.True(t, b > r)
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	data := buf.Bytes()
+
+	// Insert an eXIf chunk right after the IHDR chunk
+	ihdrEnd := len(pngSignature) + 12 + 13
+	withExif := append(append(append([]byte{}, data[:ihdrEnd]...), testPNGChunk("eXIf", testExif(1, true)[len(exifHeader):])...), data[ihdrEnd:]...)
+
+	metadata, err := readImageMetadata(withExif, "image/png")
+	assert.Nil(t, err)
+	assert.True(t, metadata.HasLocation)
+
+	stripped, _, err := stripImageMetadata(withExif, "image/png")
+	assert.Nil(t, err)
+	assert.Equal(t, data, stripped)
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	chunk := func(fourCC string, data []byte) []byte {
+		header := []byte(fourCC + "\x00\x00\x00\x00")
+		binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+		return append(header, data...)
+	}
+
+	var body []byte
+	body = append(body, chunk("VP8X", []byte{0x08 | 0x04, 0, 0, 0, 1, 0, 0, 1, 0, 0})...)
+	body = append(body, chunk("VP8L", []byte{0x2f, 0, 0, 0, 0})...)
+	body = append(body, chunk("EXIF", testExif(1, true))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), body...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	metadata, err := readImageMetadata(data, "image/webp")
+	assert.Nil(t, err)
+	assert.True(t, metadata.HasLocation)
+
+	stripped, _, err := stripImageMetadata(data, "image/webp")
+	assert.Nil(t, err)
+
+	chunks, err := webpChunks(stripped)
+	assert.Nil(t, err)
+	assert.Len(t, chunks, 2)
+	assert.Equal(t, byte(0), chunks[0].data[0], "EXIF and XMP flags are cleared")
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:]))
+}
+
+func TestImageLocationReport(t *testing.T) {
+	r := newTestRouter(t)
+
+	admin := testLogin(t, r, "ada", RoleAdmin)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	photo := testJPEGWithExif(t, testExif(1, true))
+
+	// Stored before metadata was stripped on upload
+	blobStore.Put(BlobInfo{Key: "legacy", Size: int64(len(photo)), ContentType: "image/jpeg"}, bytes.NewReader(photo))
+
+	// Uploaded now, and stripped on the way in
+	assert.Equal(t, 200, testUpload(t, r, editor, photo).Code)
+
+	assert.Equal(t, 403, testRequest(r, "GET", "/admin/images/location-report", editor, "").Code)
+
+	w := testRequest(r, "GET", "/admin/images/location-report", admin, "")
+	assert.Equal(t, 200, w.Code)
+
+	var report struct {
+		Blobs []BlobInfo `json:"blobs"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &report)
+
+	if assert.Len(t, report.Blobs, 1) {
+		assert.Equal(t, "legacy", report.Blobs[0].Key)
+	}
+}
+
+func TestStripJPEGMetadataPixelLimit(t *testing.T) {
+	maxImagePixels = 4
+	defer func() { maxImagePixels = 50000000 }()
+
+	// Applying the orientation means decoding the image, which must not be too large
+	_, _, err := stripImageMetadata(testJPEGWithExif(t, testExif(6, false)), "image/jpeg")
+	assert.Equal(t, errImageDimensions, err)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// referencedImages returns the blob keys of all images used by items, including items in the trash
+// and their revisions made since revisionsSince, so that recent edits can be rolled back with their images.
+// Images only used by older revisions are no longer referenced.
+func referencedImages(items ItemStore, revisionsSince time.Time) (keys map[string]bool, err error) {
+	keys = make(map[string]bool)
+
+	all, err := listAllItems(items)
+	if err != nil {
+		return
+	}
+
+	trash, err := items.ListTrash()
+	if err != nil {
+		return
+	}
+	all = append(all, trash...)
+
+	addImages := func(data []byte) error {
+		var images struct {
+			CoverImageURL string   `json:"coverImageURL"`
+			ImageURLs     []string `json:"imageURLs"`
+		}
+
+		if err := json.Unmarshal(data, &images); err != nil {
+			return err
+		}
+
+		keys[images.CoverImageURL] = true
+		for _, imageURL := range images.ImageURLs {
+			keys[imageURL] = true
+		}
+		return nil
+	}
+
+	for _, item := range all {
+		if err = addImages(item.Data); err != nil {
+			return
+		}
+
+		var revisions []ItemRevision
+		if revisions, err = items.ListRevisions(item.ID); err != nil {
+			return
+		}
+
+		for _, revision := range revisions {
+			if revision.CreatedAt.Before(revisionsSince) {
+				continue
+			}
+
+			if err = addImages(revision.Data); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// listAllItems fetches every item that is not in the trash, whatever its type,
+// including items stored before their data was validated
+func listAllItems(items ItemStore) (all []Item, err error) {
+	query := ItemQuery{Sort: "created_at", Order: "asc", Size: maxItemQuerySize}
+
+	for {
+		var page ItemPage
+		if page, err = items.List(query); err != nil {
+			return
+		}
+		all = append(all, page.Items...)
+
+		if page.NextCursor == nil {
+			return
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+// collectImages removes the images that no item or revision made since revisionsSince refers to
+// and that were stored before the given time, along with their derivatives.
+// With dryRun set, it only reports what would be removed.
+func collectImages(items ItemStore, blobs BlobStore, before, revisionsSince time.Time, dryRun bool) (collected []BlobInfo, err error) {
+	referenced, err := referencedImages(items, revisionsSince)
+	if err != nil {
+		return
+	}
+
+	infos, err := blobs.List("")
+	if err != nil {
+		return
+	}
+
+	remove := func(info BlobInfo) error {
+		if !dryRun {
+			if err := blobs.Delete(info.Key); err != nil {
+				return err
+			}
+		}
+
+		collected = append(collected, info)
+		return nil
+	}
+
+	// Derivatives are collected along with the image they were made from
+	derivatives := make(map[string][]BlobInfo)
+	var images []BlobInfo
+
+	for _, info := range infos {
+		if strings.HasPrefix(info.Key, "derivatives/") {
+			original := strings.SplitN(strings.TrimPrefix(info.Key, "derivatives/"), "/", 2)[0]
+			derivatives[original] = append(derivatives[original], info)
+		} else {
+			images = append(images, info)
+		}
+	}
+
+	removeWithDerivatives := func(key string) error {
+		for _, derivative := range derivatives[key] {
+			if err := remove(derivative); err != nil {
+				return err
+			}
+		}
+		delete(derivatives, key)
+		return nil
+	}
+
+	for _, info := range images {
+		if referenced[info.Key] || !info.ModTime.Before(before) {
+			continue
+		}
+
+		if err = remove(info); err != nil {
+			return
+		}
+		if err = removeWithDerivatives(info.Key); err != nil {
+			return
+		}
+	}
+
+	// Derivatives may outlive their image when it was removed some other way
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Key, "derivatives/") {
+			continue
+		}
+
+		original := strings.SplitN(strings.TrimPrefix(info.Key, "derivatives/"), "/", 2)[0]
+		if _, ok := derivatives[original]; !ok || referenced[original] || blobExists(blobs, original) {
+			continue
+		}
+
+		if err = removeWithDerivatives(original); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// blobExists reports whether a blob exists, treating errors other than ErrBlobNotFound as existing
+func blobExists(blobs BlobStore, key string) bool {
+	_, err := blobs.Stat(key)
+	return err != ErrBlobNotFound
+}
+
+// runImageCollector collects unused images every interval until the process exits
+func runImageCollector(interval, grace, revisionRetention time.Duration) {
+	for range time.Tick(interval) {
+		now := time.Now()
+		collected, err := collectImages(itemStore, blobStore, now.Add(-grace), now.Add(-revisionRetention), false)
+		if err != nil {
+			log.Error("Could not collect unused images: ", err)
+			continue
+		}
+
+		if len(collected) > 0 {
+			log.Infof("Removed %d unused image blob(s) freeing %d bytes", len(collected), totalBlobSize(collected))
+		}
+	}
+}
+
+// totalBlobSize adds up the sizes of blobs
+func totalBlobSize(infos []BlobInfo) (size int64) {
+	for _, info := range infos {
+		size += info.Size
+	}
+	return
+}
+
+// gcImagesCommand removes image blobs that no item refers to any more
+func gcImagesCommand(c *cli.Context) error {
+	grace := c.Duration("grace")
+	if grace < 0 {
+		return fmt.Errorf("grace period must not be negative")
+	}
+
+	revisionRetention := c.Duration("revision-retention")
+	if revisionRetention < 0 {
+		return fmt.Errorf("revision retention must not be negative")
+	}
+
+	db, err := openPostgres(dbConnStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = openBlobStore(c); err != nil {
+		return err
+	}
+
+	dryRun := c.Bool("dry-run")
+
+	now := time.Now()
+	collected, err := collectImages(NewPostgresItemStore(db), blobStore, now.Add(-grace), now.Add(-revisionRetention), dryRun)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSIZE\tMODIFIED AT")
+
+	for _, info := range collected {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", info.Key, info.Size, info.ModTime.Format(time.RFC3339))
+	}
+
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("Would remove %d blob(s) freeing %d bytes\n", len(collected), totalBlobSize(collected))
+	} else {
+		fmt.Printf("Removed %d blob(s) freeing %d bytes\n", len(collected), totalBlobSize(collected))
+	}
+
+	return nil
+}
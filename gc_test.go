@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectImages(t *testing.T) {
+	items := NewMemoryItemStore()
+	blobs := NewFSBlobStore(t.TempDir())
+
+	for _, key := range []string{"used", "replaced", "untyped", "trashed", "orphan", "derivatives/used/thumb", "derivatives/orphan/320", "derivatives/orphan/thumb", "derivatives/gone/thumb"} {
+		blobs.Put(BlobInfo{Key: key, Size: 10}, strings.NewReader("0123456789"))
+	}
+
+	cafe, _ := items.Create([]byte(`{"type": "location", "title": "Cafe", "coverImageURL": "replaced"}`))
+	items.Update(cafe.ID, []byte(`{"type": "location", "title": "Cafe", "coverImageURL": "used"}`), 0)
+	items.Create([]byte(`{"type": "restaurant", "coverImageURL": "untyped"}`))
+	trashed, _ := items.Create([]byte(`{"type": "event", "title": "Fair", "coverImageURL": "x", "imageURLs": ["trashed"]}`))
+	items.Delete(trashed.ID, 0)
+
+	// Images uploaded within the grace period are kept
+	collected, err := collectImages(items, blobs, time.Now().Add(-time.Hour), time.Time{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"derivatives/gone/thumb"}, blobKeys(collected))
+
+	// Images of recent revisions are kept so that edits can be rolled back
+	revisionsSince := time.Now().Add(-time.Hour)
+
+	collected, err = collectImages(items, blobs, time.Now().Add(time.Hour), revisionsSince, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"orphan", "derivatives/orphan/320", "derivatives/orphan/thumb"}, blobKeys(collected))
+	assert.Equal(t, int64(30), totalBlobSize(collected), "derivatives count towards the freed space")
+
+	// A dry run removes nothing
+	infos, _ := blobs.List("")
+	assert.Len(t, infos, 8)
+
+	collected, err = collectImages(items, blobs, time.Now().Add(time.Hour), revisionsSince, false)
+	assert.Nil(t, err)
+	assert.Len(t, collected, 3)
+
+	infos, _ = blobs.List("")
+	assert.Equal(t, []string{"derivatives/used/thumb", "replaced", "trashed", "untyped", "used"}, blobKeys(infos))
+
+	// Once the revision that used it is older than the retention, an image replaced by an edit is collected
+	collected, err = collectImages(items, blobs, time.Now().Add(time.Hour), time.Now().Add(time.Hour), false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"replaced"}, blobKeys(collected))
+}
+
+func blobKeys(infos []BlobInfo) []string {
+	keys := make([]string, 0, len(infos))
+	for _, info := range infos {
+		keys = append(keys, info.Key)
+	}
+	return keys
+}
@@ -0,0 +1,19 @@
+package main
+
+import "math"
+
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371008.8
+
+// greatCircleDistance returns the distance in meters between two points given in degrees, using the haversine formula
+func greatCircleDistance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLatitude := toRadians(latitude2 - latitude1)
+	dLongitude := toRadians(longitude2 - longitude1)
+
+	a := math.Sin(dLatitude/2)*math.Sin(dLatitude/2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Sin(dLongitude/2)*math.Sin(dLongitude/2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
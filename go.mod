@@ -1,6 +1,6 @@
 module ttd
 
-go 1.14
+go 1.16
 
 require (
 	github.com/AlecAivazis/survey/v2 v2.0.7
@@ -13,4 +13,6 @@ require (
 	github.com/stretchr/testify v1.4.0
 	github.com/urfave/cli v1.22.4 // indirect
 	github.com/urfave/cli/v2 v2.2.0
+	golang.org/x/crypto v0.0.0-20210616213533-5ff15b29337e
+	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
 )
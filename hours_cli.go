@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// osmHours is the part of location JSON that holds its opening hours
+type osmHours struct {
+	OpeningHours       map[string]string `json:"openingHours"`
+	PublicHolidayHours string            `json:"publicHolidayHours,omitempty"`
+}
+
+func hoursFromOSMCommand(c *cli.Context) error {
+	s := c.Args().First()
+	if s == "" {
+		return errors.New("opening hours are required")
+	}
+
+	var hours osmHours
+	var err error
+	if hours.OpeningHours, hours.PublicHolidayHours, err = parseOSMOpeningHours(s); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(hours, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(b))
+	return nil
+}
+
+// hoursToOSMCommand reads location JSON from a file, or from stdin when no file is given
+func hoursToOSMCommand(c *cli.Context) error {
+	var b []byte
+	var err error
+	if path := c.Args().First(); path != "" {
+		b, err = ioutil.ReadFile(path)
+	} else {
+		b, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	var hours osmHours
+	if err := json.Unmarshal(b, &hours); err != nil {
+		return err
+	}
+
+	s, err := formatOSMOpeningHours(hours.OpeningHours, hours.PublicHolidayHours)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(s)
+	return nil
+}
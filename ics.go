@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icalDateLayout     = "20060102"
+	icalTimeLayout     = "20060102T150405"
+	icalUTCTimeLayout  = "20060102T150405Z"
+	icalMaxLineLength  = 75
+	icalTimeZoneMargin = 2 // How many years after the last start time zone transitions are listed for
+)
+
+// icalWriter writes the content lines of an iCalendar object
+type icalWriter struct {
+	b strings.Builder
+}
+
+// line writes a content line, folding it so that no line is longer than 75 octets.
+// value must already be escaped.
+func (w *icalWriter) line(name, value string) {
+	s := name + ":" + value
+
+	limit := icalMaxLineLength
+	for len(s) > limit {
+		// Lines must not be folded within a UTF-8 sequence
+		i := limit
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+
+		w.b.WriteString(s[:i] + "\r\n ")
+		s = s[i:]
+		limit = icalMaxLineLength - 1
+	}
+
+	w.b.WriteString(s + "\r\n")
+}
+
+// escapeICalText escapes a TEXT value
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// icalOffset formats a UTC offset in seconds as a UTC-OFFSET value
+func icalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	s := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		s += fmt.Sprintf("%02d", offset%60)
+	}
+	return s
+}
+
+// icalTime returns the parameters and the value of a DATE or DATE-TIME property of event
+func (event *Event) icalTime(t time.Time) (params, value string) {
+	if event.AllDay {
+		return ";VALUE=DATE", t.Format(icalDateLayout)
+	}
+
+	return ";TZID=" + event.TimeZone, t.Format(icalTimeLayout)
+}
+
+// icalRRule returns the recurrence rule of the event in the form RFC 5545 requires with its start:
+// UNTIL is a date for all-day events and a UTC time for all other events
+func (event *Event) icalRRule(loc *time.Location) (string, error) {
+	rule, err := parseRRule(event.RRule, loc)
+	if err != nil {
+		return "", err
+	}
+
+	if event.AllDay && !rule.UntilDate && !rule.Until.IsZero() {
+		rule.Until = civilDate(rule.Until.In(loc))
+		rule.UntilDate = true
+	} else if !event.AllDay && rule.UntilDate {
+		rule.Until = rule.Until.AddDate(0, 0, 1).Add(-time.Second)
+		rule.UntilDate = false
+	}
+
+	return rule.String(), nil
+}
+
+// writeVEvents writes the VEVENT components of event, one for each session if it has sessions.
+// Events without a schedule have no components.
+func (w *icalWriter) writeVEvents(event Event) error {
+	periods, err := event.Periods()
+	if err != nil || len(periods) == 0 {
+		return err
+	}
+
+	loc, err := event.Location()
+	if err != nil {
+		return err
+	}
+
+	stamp := event.UpdatedAt
+	if stamp.IsZero() {
+		stamp = event.CreatedAt
+	}
+
+	for i, period := range periods {
+		w.line("BEGIN", "VEVENT")
+
+		if len(event.Sessions) > 0 {
+			w.line("UID", fmt.Sprintf("event-%d-%d@ttd", event.ID, i+1))
+		} else {
+			w.line("UID", fmt.Sprintf("event-%d@ttd", event.ID))
+		}
+
+		w.line("DTSTAMP", stamp.UTC().Format(icalUTCTimeLayout))
+		if !event.UpdatedAt.IsZero() {
+			w.line("LAST-MODIFIED", event.UpdatedAt.UTC().Format(icalUTCTimeLayout))
+		}
+
+		params, value := event.icalTime(period.Start)
+		w.line("DTSTART"+params, value)
+
+		if !period.End.Equal(period.Start) {
+			params, value = event.icalTime(period.End)
+			w.line("DTEND"+params, value)
+		}
+
+		if event.RRule != "" {
+			rule, err := event.icalRRule(loc)
+			if err != nil {
+				return err
+			}
+			w.line("RRULE", rule)
+		}
+
+		// Excluded starts only mean something for events that repeat
+		if event.Recurs() {
+			if err := w.writeEventDates("RDATE", event, event.RDates, loc); err != nil {
+				return err
+			}
+
+			if err := w.writeEventDates("EXDATE", event, event.ExDates, loc); err != nil {
+				return err
+			}
+		}
+
+		summary := event.Title
+		if period.Title != "" {
+			summary += ": " + period.Title
+		}
+		w.line("SUMMARY", escapeICalText(summary))
+
+		if event.Description != "" {
+			w.line("DESCRIPTION", escapeICalText(event.Description))
+		}
+
+		if event.Address != "" {
+			w.line("LOCATION", escapeICalText(event.Address))
+		}
+
+		if len(event.Coordinates) == 2 {
+			w.line("GEO", strconv.FormatFloat(event.Coordinates[0], 'f', -1, 64)+";"+strconv.FormatFloat(event.Coordinates[1], 'f', -1, 64))
+		}
+
+		if event.WebsiteURL != "" {
+			w.line("URL", event.WebsiteURL)
+		}
+
+		for _, tag := range event.Tags {
+			w.line("CATEGORIES", escapeICalText(tag))
+		}
+
+		w.line("END", "VEVENT")
+	}
+
+	return nil
+}
+
+// writeEventDates writes the extra or excluded starts of a recurring event
+func (w *icalWriter) writeEventDates(name string, event Event, dates []string, loc *time.Location) error {
+	if len(dates) == 0 {
+		return nil
+	}
+
+	var params string
+	values := make([]string, 0, len(dates))
+
+	for _, date := range dates {
+		t, err := parseEventTime(date, event.AllDay, loc)
+		if err != nil {
+			return err
+		}
+
+		var value string
+		params, value = event.icalTime(t)
+		values = append(values, value)
+	}
+
+	w.line(name+params, strings.Join(values, ","))
+	return nil
+}
+
+// zoneTransitions returns the times in [from, to) at which the offset or the name of loc changes
+func zoneTransitions(loc *time.Location, from, to time.Time) (transitions []time.Time) {
+	zoneAt := func(unix int64) (string, int) {
+		return time.Unix(unix, 0).In(loc).Zone()
+	}
+
+	end := to.Unix()
+	name, offset := zoneAt(from.Unix())
+
+	for t := from.Unix(); t < end; {
+		next := t + 24*60*60
+		if next > end {
+			next = end
+		}
+
+		if nextName, nextOffset := zoneAt(next); nextName != name || nextOffset != offset {
+			// Narrow the change down to the second
+			lo, hi := t, next
+			for hi-lo > 1 {
+				mid := lo + (hi-lo)/2
+				if midName, midOffset := zoneAt(mid); midName == name && midOffset == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+
+			transitions = append(transitions, time.Unix(hi, 0))
+			name, offset = zoneAt(hi)
+			next = hi
+		}
+
+		t = next
+	}
+
+	return
+}
+
+// writeVTimezone writes a VTIMEZONE component that describes loc between from and to.
+// Each transition is listed as its own observance, so that no recurrence rules have to be derived.
+func (w *icalWriter) writeVTimezone(tzid string, loc *time.Location, from, to time.Time) {
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", tzid)
+
+	transitions := zoneTransitions(loc, from, to)
+	name, offset := from.In(loc).Zone()
+
+	// The first observance is daylight saving time when the zone later falls back from it
+	component := "STANDARD"
+	if len(transitions) > 0 {
+		if _, nextOffset := transitions[0].In(loc).Zone(); nextOffset < offset {
+			component = "DAYLIGHT"
+		}
+	}
+
+	w.writeObservance(component, from.In(time.FixedZone("", offset)), offset, offset, name)
+
+	for _, transition := range transitions {
+		nextName, nextOffset := transition.In(loc).Zone()
+
+		component = "STANDARD"
+		if nextOffset > offset {
+			component = "DAYLIGHT"
+		}
+
+		// Observances start at the local time that was in effect right before them
+		w.writeObservance(component, transition.In(time.FixedZone("", offset)), offset, nextOffset, nextName)
+		name, offset = nextName, nextOffset
+	}
+
+	w.line("END", "VTIMEZONE")
+}
+
+func (w *icalWriter) writeObservance(component string, start time.Time, offsetFrom, offsetTo int, name string) {
+	w.line("BEGIN", component)
+	w.line("DTSTART", start.Format(icalTimeLayout))
+	w.line("TZOFFSETFROM", icalOffset(offsetFrom))
+	w.line("TZOFFSETTO", icalOffset(offsetTo))
+	if name != "" {
+		w.line("TZNAME", escapeICalText(name))
+	}
+	w.line("END", component)
+}
+
+// eventCalendar returns an RFC 5545 VCALENDAR with the events that have a schedule.
+// The time zones of timed events are described from the year of their first start
+// until a few years after their last start or now, whichever is later.
+func eventCalendar(name string, events []Event, now time.Time) ([]byte, error) {
+	type zoneRange struct {
+		loc      *time.Location
+		from, to time.Time
+	}
+
+	var zoneIDs []string
+	zones := make(map[string]*zoneRange)
+	var components icalWriter
+
+	for _, event := range events {
+		periods, err := event.Periods()
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %s", event.ID, err)
+		}
+
+		if len(periods) == 0 {
+			continue
+		}
+
+		if err := components.writeVEvents(event); err != nil {
+			return nil, fmt.Errorf("event %d: %s", event.ID, err)
+		}
+
+		if event.AllDay {
+			continue
+		}
+
+		loc, _ := event.Location()
+		last := now
+		for _, period := range periods {
+			if period.Start.After(last) {
+				last = period.Start
+			}
+		}
+
+		for _, rdate := range event.RDates {
+			if start, err := parseEventTime(rdate, false, loc); err == nil && start.After(last) {
+				last = start
+			}
+		}
+
+		from := time.Date(periods[0].Start.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(last.Year()+icalTimeZoneMargin+1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		if zone, ok := zones[event.TimeZone]; !ok {
+			zones[event.TimeZone] = &zoneRange{loc, from, to}
+			zoneIDs = append(zoneIDs, event.TimeZone)
+		} else {
+			if from.Before(zone.from) {
+				zone.from = from
+			}
+			if to.After(zone.to) {
+				zone.to = to
+			}
+		}
+	}
+
+	var w icalWriter
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//ttd//Events//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", escapeICalText(name))
+
+	for _, tzid := range zoneIDs {
+		zone := zones[tzid]
+		w.writeVTimezone(tzid, zone.loc, zone.from, zone.to)
+	}
+
+	w.b.WriteString(components.b.String())
+	w.line("END", "VCALENDAR")
+
+	return []byte(w.b.String()), nil
+}
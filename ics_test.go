@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICalLineFolding(t *testing.T) {
+	var w icalWriter
+	w.line("DESCRIPTION", escapeICalText(strings.Repeat("é", 80)+"\nA; B, C\\D"))
+
+	lines := strings.Split(strings.TrimSuffix(w.b.String(), "\r\n"), "\r\n")
+	assert.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.True(t, len(line) <= 75, line)
+	}
+
+	unfolded := strings.ReplaceAll(w.b.String(), "\r\n ", "")
+	assert.Equal(t, "DESCRIPTION:"+strings.Repeat("é", 80)+`\nA\; B\, C\\D`+"\r\n", unfolded)
+}
+
+func TestEventCalendar(t *testing.T) {
+	updatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{
+			ID:          3,
+			Title:       "Quiz night",
+			Description: "Teams of four",
+			Address:     "1 Main Street, Berlin",
+			Coordinates: []float64{52.52, 13.405},
+			WebsiteURL:  "https://example.com/quiz",
+			StartsAt:    "2026-03-21T19:00",
+			EndsAt:      "2026-03-21T21:30",
+			TimeZone:    "Europe/Berlin",
+			RRule:       "FREQ=WEEKLY;UNTIL=20260404",
+			ExDates:     []string{"2026-03-28T19:00"},
+			UpdatedAt:   updatedAt,
+		},
+		{ID: 4, Title: "Fair", StartsAt: "2026-05-01", EndsAt: "2026-05-02", TimeZone: "Asia/Singapore", AllDay: true, UpdatedAt: updatedAt},
+		{ID: 5, Title: "Unscheduled"},
+	}
+
+	data, err := eventCalendar("Events", events, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ics := string(data)
+	for _, line := range []string{
+		"BEGIN:VCALENDAR",
+		"TZID:Europe/Berlin",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20260329T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT",
+		"UID:event-3@ttd",
+		"DTSTAMP:20260301T120000Z",
+		"DTSTART;TZID=Europe/Berlin:20260321T190000",
+		"DTEND;TZID=Europe/Berlin:20260321T213000",
+		"RRULE:FREQ=WEEKLY;UNTIL=20260404T215959Z",
+		"EXDATE;TZID=Europe/Berlin:20260328T190000",
+		"SUMMARY:Quiz night",
+		"LOCATION:1 Main Street\\, Berlin",
+		"GEO:52.52;13.405",
+		"URL:https://example.com/quiz",
+		"DTSTART;VALUE=DATE:20260501",
+		"DTEND;VALUE=DATE:20260503",
+	} {
+		assert.Contains(t, ics, line+"\r\n")
+	}
+
+	assert.Equal(t, 2, strings.Count(ics, "BEGIN:VEVENT"))
+	assert.NotContains(t, ics, "TZID:Asia/Singapore", "all-day events don't need a time zone")
+}
+
+func TestGetEventsFeed(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	market := `{"type": "event", "title": "Market", "coverImageURL": "abc", "startsAt": "2026-05-02T08:00",
+		"timeZone": "Asia/Singapore", "tags": ["food"]}`
+	talk := `{"type": "event", "title": "Talk", "coverImageURL": "abc", "startsAt": "2026-05-09T19:00", "timeZone": "Europe/London"}`
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, market).Code)
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, talk).Code)
+
+	// Calendar apps fetch the feed without signing in
+	w := testRequest(r, "GET", "/feeds/events.ics?tag=food", "", "")
+	if !assert.Equal(t, 200, w.Code) {
+		return
+	}
+
+	assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "SUMMARY:Market\r\n")
+	assert.NotContains(t, w.Body.String(), "SUMMARY:Talk")
+	assert.Contains(t, w.Body.String(), "X-WR-CALNAME:Events tagged food\r\n")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// imageExtensions maps the content types of the image formats that can be stored
+// to the file extension they are published with
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// errImageType is returned when an image is not one of the formats in imageExtensions
+var errImageType = errors.New("image must be a JPEG, PNG, GIF, WebP or SVG file")
+
+// maxImagePixels is the largest number of pixels an image may have to be decoded.
+// Compressed images can be small files that still decode into gigabytes.
+var maxImagePixels = int64(50000000)
+
+// errImageDimensions is returned when an image has more than maxImagePixels pixels
+var errImageDimensions = fmt.Errorf("image must not have more than %d pixels", maxImagePixels)
+
+// checkImageDimensions returns errImageDimensions when config describes an image with too many pixels
+func checkImageDimensions(config image.Config) error {
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return errImageDimensions
+	}
+	return nil
+}
+
+// decodeImage decodes a raster image once its header shows that it isn't too large to decode.
+// It returns errImageType when data is not an image it can decode.
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errImageType
+	}
+
+	if err := checkImageDimensions(config); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errImageType
+	}
+
+	return img, nil
+}
+
+// detectImageType sniffs the content type of image data from its first 512 bytes.
+// Unlike http.DetectContentType, it recognizes SVG documents.
+func detectImageType(head []byte) string {
+	contentType := http.DetectContentType(head)
+
+	if strings.HasPrefix(contentType, "text/xml") || strings.HasPrefix(contentType, "text/plain") {
+		if bytes.Contains(head, []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+
+	return contentType
+}
+
+// blobImageExtension returns the file extension of a stored image.
+// Images stored before content types were kept with blobs are sniffed.
+func blobImageExtension(key string) (string, error) {
+	info, err := blobStore.Stat(key)
+	if err != nil {
+		return "", err
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		r, _, err := blobStore.Get(key)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+
+		head := make([]byte, 512)
+		n, err := io.ReadFull(r, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return "", err
+		}
+
+		contentType = detectImageType(head[:n])
+	}
+
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return "", errImageType
+	}
+
+	return ext, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectImageType(t *testing.T) {
+	assert.Equal(t, "image/png", detectImageType(testPNG(t)))
+	assert.Equal(t, "image/svg+xml", detectImageType([]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`)))
+	assert.Equal(t, "text/plain; charset=utf-8", detectImageType([]byte("hello")))
+}
+
+func TestZolaImageExtensions(t *testing.T) {
+	blobStore = NewFSBlobStore(t.TempDir())
+
+	png := testPNG(t)
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)
+
+	// A blob stored without a content type is sniffed
+	blobStore.Put(BlobInfo{Key: "legacy", Size: int64(len(png))}, bytes.NewReader(png))
+
+	data := map[string]interface{}{
+		"coverImageURL": "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(svg),
+		"imageURLs":     []interface{}{"legacy"},
+	}
+	assert.Nil(t, storeImages(data))
+
+	location := Location{
+		ID:            7,
+		CoverImageURL: data["coverImageURL"].(string),
+		ImageURLs:     data["imageURLs"].([]string),
+	}
+
+	zolaLocation, err := location.Zola(time.Now())
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(zolaLocation.Extra.CoverImageURL, ".svg"))
+	assert.Equal(t, []string{"/img/location/7/legacy.png"}, zolaLocation.Extra.ImageURLs)
+
+	data = map[string]interface{}{
+		"coverImageURL": "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("not a png")),
+	}
+	assert.Equal(t, errImageType, storeImages(data))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+
+	"golang.org/x/image/draw"
+)
+
+// ImageMeta describes a stored image, so that pages can reserve space for it
+// and show a placeholder while it loads
+type ImageMeta struct {
+	ID            string `json:"id"`
+	ContentType   string `json:"contentType"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	Size          int64  `json:"size"`
+	DominantColor string `json:"dominantColor"` // such as "#a0b1c2"
+	Blurhash      string `json:"blurhash"`
+}
+
+// describeImage computes the blob metadata of an image: its pixel dimensions,
+// dominant color and blurhash. Vector images have no such metadata.
+func describeImage(data []byte, contentType string) (metadata map[string]string, err error) {
+	if contentType == "image/svg+xml" {
+		return
+	}
+
+	img, err := decodeImage(data)
+	if err != nil {
+		return
+	}
+
+	bounds := img.Bounds()
+
+	// The color and blurhash only need a rough idea of the image
+	small := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, bounds, draw.Src, nil)
+
+	metadata = map[string]string{
+		"width":          strconv.Itoa(bounds.Dx()),
+		"height":         strconv.Itoa(bounds.Dy()),
+		"dominant-color": dominantColor(small),
+		"blurhash":       blurhash(small, 4, 3),
+	}
+	return
+}
+
+// dominantColor returns the most common color of img as a hex color.
+// Similar colors are counted together, and the result is their average.
+func dominantColor(img *image.RGBA) string {
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+
+	buckets := make(map[int]*bucket)
+	var best *bucket
+
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		r, g, b, a := int(img.Pix[i]), int(img.Pix[i+1]), int(img.Pix[i+2]), int(img.Pix[i+3])
+
+		// Transparent pixels are not part of what is seen
+		if a < 128 {
+			continue
+		}
+
+		key := r>>4<<8 | g>>4<<4 | b>>4
+		if buckets[key] == nil {
+			buckets[key] = &bucket{}
+		}
+
+		bb := buckets[key]
+		bb.count++
+		bb.r += r
+		bb.g += g
+		bb.b += b
+
+		if best == nil || bb.count > best.count {
+			best = bb
+		}
+	}
+
+	if best == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
+
+// loadImageMeta describes the image stored under key. Metadata is computed when it is missing,
+// as it is for images stored before it was introduced, but the blob is left as it is:
+// reads must not rewrite blobs, which would also reset the time the image collector goes by.
+func loadImageMeta(key string) (meta ImageMeta, err error) {
+	info, err := blobStore.Stat(key)
+	if err != nil {
+		return
+	}
+
+	if info.Metadata["width"] == "" && info.ContentType != "image/svg+xml" {
+		var data []byte
+		if data, err = readBlob(blobStore, key); err != nil {
+			return
+		}
+
+		if info.ContentType == "" {
+			info.ContentType = detectImageType(data)
+		}
+
+		if info.Metadata, err = describeImage(data, info.ContentType); err != nil {
+			return
+		}
+	}
+
+	meta = ImageMeta{
+		ID:            key,
+		ContentType:   info.ContentType,
+		Size:          info.Size,
+		DominantColor: info.Metadata["dominant-color"],
+		Blurhash:      info.Metadata["blurhash"],
+	}
+	meta.Width, _ = strconv.Atoi(info.Metadata["width"])
+	meta.Height, _ = strconv.Atoi(info.Metadata["height"])
+	return
+}
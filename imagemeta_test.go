@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlurhash(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(white, white.Bounds(), image.White, image.Point{}, draw.Src)
+
+	assert.Equal(t, "LfTSUA~qfQ~q~qt7fQt7fQfQfQfQ", blurhash(white, 4, 3))
+	assert.Equal(t, "00TSUA", blurhash(white, 1, 1))
+
+	gradient := image.NewRGBA(image.Rect(0, 0, 16, 12))
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 16; x++ {
+			gradient.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 20), 128, 255})
+		}
+	}
+
+	assert.Equal(t, "LsGuUU2@wxozqlR-jte=g0fjfQfj", blurhash(gradient, 4, 3))
+}
+
+func TestDominantColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{200, 16, 32, 255}}, image.Point{}, draw.Src)
+	img.Set(0, 0, color.RGBA{0, 0, 255, 255})
+	img.Set(1, 0, color.RGBA{0, 0, 0, 0})
+
+	assert.Equal(t, "#c81020", dominantColor(img))
+}
+
+func TestGetImageMeta(t *testing.T) {
+	r := newTestRouter(t)
+
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	var upload struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(testUpload(t, r, editor, testJPEG(t, 40, 30)).Body.Bytes(), &upload)
+
+	// Stored before metadata was computed on upload
+	photo := testJPEG(t, 20, 10)
+	blobStore.Put(BlobInfo{Key: "legacy", Size: int64(len(photo)), ContentType: "image/jpeg"}, bytes.NewReader(photo))
+	legacy, _ := blobStore.Stat("legacy")
+
+	for _, expected := range []ImageMeta{
+		{ID: upload.ID, Width: 40, Height: 30},
+		{ID: "legacy", Width: 20, Height: 10},
+	} {
+		w := testRequest(r, "GET", "/images/"+expected.ID+"/meta", editor, "")
+		assert.Equal(t, 200, w.Code)
+
+		var meta ImageMeta
+		json.Unmarshal(w.Body.Bytes(), &meta)
+
+		assert.Equal(t, expected.Width, meta.Width)
+		assert.Equal(t, expected.Height, meta.Height)
+		assert.Equal(t, "image/jpeg", meta.ContentType)
+		assert.Equal(t, "#000000", meta.DominantColor)
+		assert.Len(t, meta.Blurhash, 28)
+		assert.True(t, meta.Size > 0)
+	}
+
+	info, _ := blobStore.Stat("legacy")
+	assert.Empty(t, info.Metadata["width"], "reading metadata leaves the blob as it is")
+	assert.Equal(t, legacy.ModTime, info.ModTime)
+
+	assert.Equal(t, 404, testRequest(r, "GET", "/images/missing/meta", editor, "").Code)
+}
+
+func TestGenerateEventContentImageMeta(t *testing.T) {
+	itemStore = NewMemoryItemStore()
+	blobStore = NewFSBlobStore(t.TempDir())
+	zolaPath = t.TempDir()
+	defer func() { zolaPath = "" }()
+
+	os.MkdirAll(filepath.Join(zolaPath, "content", "events"), 0700)
+
+	photo := testJPEG(t, 40, 30)
+	metadata, err := describeImage(photo, "image/jpeg")
+	assert.Nil(t, err)
+	blobStore.Put(BlobInfo{Key: "photo", Size: int64(len(photo)), ContentType: "image/jpeg", Metadata: metadata}, bytes.NewReader(photo))
+
+	assert.Nil(t, generateEventContent(Event{ID: 5, Type: "event", Title: "Fair", CoverImageURL: "photo"}, eventPagesSeries))
+
+	page, _ := os.ReadFile(filepath.Join(zolaPath, "content", "events", "5.md"))
+	assert.Contains(t, string(page), `blurhash = "`+metadata["blurhash"]+`"`)
+	assert.Contains(t, string(page), `dominant_color = "#000000"`)
+	assert.Contains(t, string(page), `width = 40`)
+}
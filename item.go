@@ -2,26 +2,32 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
 type Item struct {
-	ID        int64     `json:"id"`
-	Data      []byte    `json:"data"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        int64      `json:"id"`
+	Version   int64      `json:"version"` // incremented on every change
+	Data      []byte     `json:"data"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"` // set while the item is in the trash
 }
 
 type DecodedItem struct {
 	ID        int64                  `json:"id"`
+	Version   int64                  `json:"version"`
 	Data      map[string]interface{} `json:"data"`
 	CreatedAt time.Time              `json:"createdAt"`
 	UpdatedAt time.Time              `json:"updatedAt"`
+	DeletedAt *time.Time             `json:"deletedAt,omitempty"`
 }
 
 // Decode turns Item into DecodedItem
 func (item *Item) Decode() (decodedItem DecodedItem, err error) {
 	decodedItem.ID = item.ID
+	decodedItem.Version = item.Version
 
 	if err = json.Unmarshal(item.Data, &decodedItem.Data); err != nil {
 		return
@@ -29,10 +35,23 @@ func (item *Item) Decode() (decodedItem DecodedItem, err error) {
 
 	decodedItem.CreatedAt = item.CreatedAt
 	decodedItem.UpdatedAt = item.UpdatedAt
+	decodedItem.DeletedAt = item.DeletedAt
 	return
 }
 
+// ETag returns the entity tag identifying the current version of the item
+func (item *Item) ETag() string {
+	return fmt.Sprintf("\"%d-%d\"", item.ID, item.Version)
+}
+
 // ItemCommonData is just a one-off structure for retrieving "type" from an Item's data
 type ItemCommonData struct {
 	Type string `json:"type"`
 }
+
+// itemType returns the "type" of item data, or an empty string if it can't be read
+func itemType(data []byte) string {
+	var itemCommonData ItemCommonData
+	json.Unmarshal(data, &itemCommonData)
+	return itemCommonData.Type
+}
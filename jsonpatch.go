@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchOperation is a single operation of an RFC 6902 JSON patch
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON always includes the value of operations that require one, even when it is null
+func (op JSONPatchOperation) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"op":   op.Op,
+		"path": op.Path,
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		m["value"] = op.Value
+	case "move", "copy":
+		m["from"] = op.From
+	}
+
+	return json.Marshal(m)
+}
+
+// escapeJSONPointer escapes a single reference token of an RFC 6901 JSON pointer
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// diffJSON returns the operations that turn the decoded JSON document a into b.
+// Objects are compared key by key, while arrays are replaced as a whole.
+func diffJSON(a, b interface{}) []JSONPatchOperation {
+	ops := make([]JSONPatchOperation, 0)
+	return appendJSONDiff(ops, "", a, b)
+}
+
+func appendJSONDiff(ops []JSONPatchOperation, path string, a, b interface{}) []JSONPatchOperation {
+	am, aIsObject := a.(map[string]interface{})
+	bm, bIsObject := b.(map[string]interface{})
+
+	if !aIsObject || !bIsObject {
+		if !reflect.DeepEqual(a, b) {
+			ops = append(ops, JSONPatchOperation{Op: "replace", Path: path, Value: b})
+		}
+		return ops
+	}
+
+	keys := make([]string, 0, len(am)+len(bm))
+	for k := range am {
+		keys = append(keys, k)
+	}
+	for k := range bm {
+		if _, ok := am[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "/" + escapeJSONPointer(k)
+		av, inA := am[k]
+		bv, inB := bm[k]
+
+		switch {
+		case !inB:
+			ops = append(ops, JSONPatchOperation{Op: "remove", Path: childPath})
+		case !inA:
+			ops = append(ops, JSONPatchOperation{Op: "add", Path: childPath, Value: bv})
+		default:
+			ops = appendJSONDiff(ops, childPath, av, bv)
+		}
+	}
+
+	return ops
+}
+
+// mergePatch applies an RFC 7386 JSON merge patch to the decoded JSON document target
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for k, v := range patchObject {
+		if v == nil {
+			delete(targetObject, k)
+		} else {
+			targetObject[k] = mergePatch(targetObject[k], v)
+		}
+	}
+
+	return targetObject
+}
+
+// jsonPatchRequestOperation is an operation of an RFC 6902 JSON patch as sent by clients.
+// The value is kept raw so that a null value can be told apart from a missing one.
+type jsonPatchRequestOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies an RFC 6902 JSON patch to the decoded JSON document doc.
+// The document may be modified in place, so callers should use the returned document.
+func applyJSONPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var ops []jsonPatchRequestOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, errors.New("JSON patch must be an array of operations")
+	}
+
+	for i, op := range ops {
+		var err error
+		if doc, err = applyJSONPatchOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %s", i, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyJSONPatchOperation(doc interface{}, op jsonPatchRequestOperation) (interface{}, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, errors.New("value is required")
+		}
+
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return jsonPointerAdd(doc, path, value)
+	case "remove":
+		doc, _, err = jsonPointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		if doc, _, err = jsonPointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, value)
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if isJSONPointerPrefix(from, path) && len(from) < len(path) {
+				return nil, errors.New("cannot move a value into one of its children")
+			}
+
+			if doc, value, err = jsonPointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = jsonPointerGet(doc, from); err != nil {
+				return nil, err
+			}
+
+			// Copies must not share maps or slices with the original
+			data, _ := json.Marshal(value)
+			json.Unmarshal(data, &value)
+		}
+
+		return jsonPointerAdd(doc, path, value)
+	case "test":
+		current, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(current, value) {
+			return nil, errors.New("test failed")
+		}
+
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation \"%s\"", op.Op)
+	}
+}
+
+// parseJSONPointer splits an RFC 6901 JSON pointer into unescaped reference tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("\"%s\" is not a valid JSON pointer", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func isJSONPointerPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonArrayIndex parses a reference token used on an array.
+// When allowEnd is set, "-" and the length of the array refer to the end of the array.
+func jsonArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("\"%s\" is not a valid array index", token)
+	}
+
+	if index > length || (index == length && !allowEnd) {
+		return 0, fmt.Errorf("array index %d is out of bounds", index)
+	}
+
+	return index, nil
+}
+
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member \"%s\" does not exist", token)
+			}
+			doc = value
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("cannot reference \"%s\" in a scalar value", token)
+		}
+	}
+
+	return doc, nil
+}
+
+// jsonPointerModify calls fn with the container of the value at path and the last reference token,
+// and puts the container returned by fn back in its place
+func jsonPointerModify(doc interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	child, err := jsonPointerGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	if child, err = jsonPointerModify(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[path[0]] = child
+	case []interface{}:
+		index, _ := jsonArrayIndex(path[0], len(node), false)
+		node[index] = child
+	}
+
+	return doc, nil
+}
+
+func jsonPointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return jsonPointerModify(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch node := container.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("cannot add \"%s\" to a scalar value", token)
+		}
+	})
+}
+
+func jsonPointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	var removed interface{}
+
+	doc, err := jsonPointerModify(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch node := container.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member \"%s\" does not exist", token)
+			}
+
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+
+			removed = node[index]
+			return append(node[:index:index], node[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove \"%s\" from a scalar value", token)
+		}
+	})
+
+	return doc, removed, err
+}
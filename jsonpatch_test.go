@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffJSON(t *testing.T) {
+	var a, b interface{}
+	json.Unmarshal([]byte(`{"title": "Cafe", "tags": ["a"], "extra": {"x/y": 1, "z": 2}, "phone": "123"}`), &a)
+	json.Unmarshal([]byte(`{"title": "Café", "tags": ["a", "b"], "extra": {"x/y": 1, "w": null}, "address": "Main St"}`), &b)
+
+	ops := diffJSON(a, b)
+
+	assert.Equal(t, []JSONPatchOperation{
+		{Op: "add", Path: "/address", Value: "Main St"},
+		{Op: "add", Path: "/extra/w", Value: nil},
+		{Op: "remove", Path: "/extra/z"},
+		{Op: "remove", Path: "/phone"},
+		{Op: "replace", Path: "/tags", Value: []interface{}{"a", "b"}},
+		{Op: "replace", Path: "/title", Value: "Café"},
+	}, ops)
+
+	data, err := json.Marshal(ops[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"op": "add", "path": "/extra/w", "value": null}`, string(data))
+}
+
+func TestMergePatch(t *testing.T) {
+	var target, patch interface{}
+	json.Unmarshal([]byte(`{"title": "Cafe", "phone": "123", "extra": {"a": 1, "b": 2}}`), &target)
+	json.Unmarshal([]byte(`{"title": "Café", "phone": null, "extra": {"b": null, "c": 3}, "tags": ["x"]}`), &patch)
+
+	result, _ := json.Marshal(mergePatch(target, patch))
+	assert.JSONEq(t, `{"title": "Café", "extra": {"a": 1, "c": 3}, "tags": ["x"]}`, string(result))
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"title": "Cafe", "tags": ["a", "c"], "phone": "123", "imageURLs": ["x", "y"]}`), &doc)
+
+	patch := []byte(`[
+		{"op": "test", "path": "/title", "value": "Cafe"},
+		{"op": "replace", "path": "/title", "value": "Café"},
+		{"op": "add", "path": "/tags/1", "value": "b"},
+		{"op": "add", "path": "/tags/-", "value": "d"},
+		{"op": "remove", "path": "/phone"},
+		{"op": "move", "from": "/imageURLs/0", "path": "/coverImageURL"},
+		{"op": "copy", "from": "/tags", "path": "/labels"}
+	]`)
+
+	doc, err := applyJSONPatch(doc, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, _ := json.Marshal(doc)
+	assert.JSONEq(t, `{
+		"title": "Café",
+		"tags": ["a", "b", "c", "d"],
+		"labels": ["a", "b", "c", "d"],
+		"imageURLs": ["y"],
+		"coverImageURL": "x"
+	}`, string(result))
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	for _, patch := range []string{
+		`{"op": "add"}`,
+		`[{"op": "test", "path": "/title", "value": "Other"}]`,
+		`[{"op": "remove", "path": "/missing"}]`,
+		`[{"op": "add", "path": "/tags/5", "value": "x"}]`,
+		`[{"op": "replace", "path": "/title"}]`,
+		`[{"op": "frobnicate", "path": "/title"}]`,
+	} {
+		var doc interface{}
+		json.Unmarshal([]byte(`{"title": "Cafe", "tags": []}`), &doc)
+
+		_, err := applyJSONPatch(doc, []byte(patch))
+		assert.Error(t, err, patch)
+	}
+}
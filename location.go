@@ -22,7 +22,26 @@ type ZolaLocation struct {
 		WebsiteURL    string                           `toml:"website_url"`
 		CoverImageURL string                           `toml:"cover_image_url"`
 		ImageURLs     []string                         `toml:"image_urls"`
+		CoverImage    ZolaImage                        `toml:"cover_image"`
+		Images        []ZolaImage                      `toml:"images"`
 		OpeningHours  map[string][]LocationOpeningHour `toml:"opening_hours"`
+
+		// Whether the location is open at all, and the days on which its opening hours differ
+		Status                 string                      `toml:"status"`
+		OpeningHoursExceptions []ZolaOpeningHoursException `toml:"opening_hours_exceptions"`
+		SeasonalOpeningHours   []ZolaSeasonalOpeningHours  `toml:"seasonal_opening_hours"`
+
+		// The hours on public holidays, and all of the opening hours in the OpenStreetMap syntax
+		PublicHolidaysClosed bool                  `toml:"public_holidays_closed"`
+		PublicHolidayHours   []LocationOpeningHour `toml:"public_holiday_hours"`
+		OpeningHoursOSM      string                `toml:"opening_hours_osm"`
+
+		// The time zone of the opening hours
+		TimeZone string `toml:"time_zone"`
+
+		// Tables of the weekly opening hours from Monday, and of the opening hours on the week after the site was generated
+		WeeklyHours   []ZolaOpeningDay `toml:"weekly_hours"`
+		UpcomingHours []ZolaOpeningDay `toml:"upcoming_hours"`
 	} `toml:"extra"`
 	CreatedAt time.Time `toml:"date"`
 	UpdatedAt time.Time `toml:"updated_at"`
@@ -32,20 +51,31 @@ type ZolaLocation struct {
 // For example, a store name, address, opening hours, phone number,
 // website URL, etc..
 type Location struct {
-	ID            int64             `toml:"id"`
-	Type          string            `toml:"type"`
-	Title         string            `toml:"title"`
-	Description   string            `toml:"description"`
-	Address       string            `toml:"address"`
-	Coordinates   []float64         `toml:"coordinates"`
-	Phone         string            `toml:"phone"`
+	ID            int64             `toml:"id" json:"id"`
+	Type          string            `toml:"type" json:"type"`
+	Title         string            `toml:"title" json:"title"`
+	Description   string            `toml:"description" json:"description"`
+	Address       string            `toml:"address" json:"address"`
+	Coordinates   []float64         `toml:"coordinates" json:"coordinates"`
+	Phone         string            `toml:"phone" json:"phone"`
 	WebsiteURL    string            `toml:"website_url" json:"websiteURL"`
 	CoverImageURL string            `toml:"cover_image_url" json:"coverImageURL"`
 	ImageURLs     []string          `toml:"image_urls" json:"imageURLs"`
-	Tags          []string          `toml:"tags"`
+	Tags          []string          `toml:"tags" json:"tags"`
 	OpeningHours  map[string]string `toml:"opening_hours" json:"openingHours"`
-	CreatedAt     time.Time         `toml:"created_at"`
-	UpdatedAt     time.Time         `toml:"updated_at"`
+	CreatedAt     time.Time         `toml:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time         `toml:"updated_at" json:"updatedAt"`
+
+	// Status is LocationStatusOpen, or LocationStatusClosedIndefinitely when the opening hours don't apply
+	Status                 string                  `toml:"status" json:"status,omitempty"`
+	OpeningHoursExceptions []OpeningHoursException `toml:"opening_hours_exceptions" json:"openingHoursExceptions,omitempty"`
+	SeasonalOpeningHours   []SeasonalOpeningHours  `toml:"seasonal_opening_hours" json:"seasonalOpeningHours,omitempty"`
+
+	// PublicHolidayHours replaces the opening hours on public holidays, or is "closed"
+	PublicHolidayHours string `toml:"public_holiday_hours" json:"publicHolidayHours,omitempty"`
+
+	// TimeZone is the IANA time zone of the opening hours, which is required along with Coordinates
+	TimeZone string `toml:"time_zone" json:"timeZone,omitempty"`
 }
 
 // LocationFromData converts a JSONB byte-array into a Location structure
@@ -68,8 +98,9 @@ func LocationFromItem(item Item) (location Location, err error) {
 	return
 }
 
-// Zola converts native format of Location into ZolaLocation
-func (location *Location) Zola() (zolaLocation ZolaLocation, err error) {
+// Zola converts native format of Location into ZolaLocation.
+// The table of upcoming opening hours starts on the date of now.
+func (location *Location) Zola(now time.Time) (zolaLocation ZolaLocation, err error) {
 	zolaLocation.ID = location.ID
 	zolaLocation.Title = location.Title
 	zolaLocation.Extra.Type = location.Type
@@ -80,31 +111,73 @@ func (location *Location) Zola() (zolaLocation ZolaLocation, err error) {
 	zolaLocation.Extra.WebsiteURL = location.WebsiteURL
 
 	// Append base path for images to be loaded by Zola
-	zolaLocation.Extra.CoverImageURL = fmt.Sprintf("/img/cover/location/%s.jpg", location.CoverImageURL)
+	ext, err := blobImageExtension(location.CoverImageURL)
+	if err != nil {
+		return
+	}
+	zolaLocation.Extra.CoverImageURL = fmt.Sprintf("/img/cover/location/%s%s", location.CoverImageURL, ext)
 	for _, imageURL := range location.ImageURLs {
-		zolaLocation.Extra.ImageURLs = append(zolaLocation.Extra.ImageURLs, fmt.Sprintf("/img/location/%d/%s.jpg", location.ID, imageURL))
+		if ext, err = blobImageExtension(imageURL); err != nil {
+			return
+		}
+		zolaLocation.Extra.ImageURLs = append(zolaLocation.Extra.ImageURLs, fmt.Sprintf("/img/location/%d/%s%s", location.ID, imageURL, ext))
 	}
 
 	zolaLocation.Taxonomies.Tags = location.Tags
-	zolaLocation.Extra.OpeningHours, err = location.ZolaOpeningHours()
-	zolaLocation.CreatedAt = location.CreatedAt
-	zolaLocation.UpdatedAt = location.UpdatedAt
-	return
-}
+	if zolaLocation.Extra.OpeningHours, err = location.ZolaOpeningHours(); err != nil {
+		return
+	}
 
-// ZolaOpeningHours converts the OpeningHours representation in Location into the Zola counterpart
-func (location *Location) ZolaOpeningHours() (m map[string][]LocationOpeningHour, err error) {
-	m = make(map[string][]LocationOpeningHour)
+	zolaLocation.Extra.Status = location.Status
+	if zolaLocation.Extra.Status == "" {
+		zolaLocation.Extra.Status = LocationStatusOpen
+	}
+
+	if zolaLocation.Extra.OpeningHoursExceptions, err = location.ZolaOpeningHoursExceptions(); err != nil {
+		return
+	}
+
+	if zolaLocation.Extra.SeasonalOpeningHours, err = location.ZolaSeasonalOpeningHours(); err != nil {
+		return
+	}
 
-	for k, v := range location.OpeningHours {
-		if m[k], err = parseOpeningHours(v); err != nil {
+	if location.PublicHolidayHours == publicHolidaysClosed {
+		zolaLocation.Extra.PublicHolidaysClosed = true
+	} else if location.PublicHolidayHours != "" {
+		if zolaLocation.Extra.PublicHolidayHours, err = parseOpeningHours(location.PublicHolidayHours); err != nil {
 			return
 		}
 	}
 
+	if zolaLocation.Extra.OpeningHoursOSM, err = formatOSMOpeningHours(location.OpeningHours, location.PublicHolidayHours); err != nil {
+		return
+	}
+
+	if zolaLocation.Extra.WeeklyHours, err = location.ZolaWeeklyTable(); err != nil {
+		return
+	}
+
+	// Locations without a time zone are taken to be in the time zone of the server
+	zolaLocation.Extra.TimeZone = location.TimeZone
+	loc, err := location.TimeLocation(time.Local)
+	if err != nil {
+		return
+	}
+
+	if zolaLocation.Extra.UpcomingHours, err = location.ZolaUpcomingTable(now.In(loc)); err != nil {
+		return
+	}
+
+	zolaLocation.CreatedAt = location.CreatedAt
+	zolaLocation.UpdatedAt = location.UpdatedAt
 	return
 }
 
+// ZolaOpeningHours converts the OpeningHours representation in Location into the Zola counterpart
+func (location *Location) ZolaOpeningHours() (m map[string][]LocationOpeningHour, err error) {
+	return zolaWeeklyOpeningHours(location.OpeningHours)
+}
+
 // LocationOpeningHour is structure used to store the opening time range of a locaton
 //
 // For example, a store that opens between 9.30AM to 5 PM would have data such as this:
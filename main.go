@@ -1,8 +1,9 @@
 package main
 
 import (
-	"database/sql"
 	"os"
+	"time"
+	_ "time/tzdata"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -13,12 +14,12 @@ import (
 var (
 	zolaPath  string // The path to the Zola directory
 	dbConnStr string // The database connection string
-)
 
-func dbConn() (db *sql.DB, err error) {
-	db, err = sql.Open("postgres", dbConnStr)
-	return
-}
+	itemStore   ItemStore   // The store used by the API handlers
+	userStore   UserStore   // The store of user accounts and their sessions
+	apiKeyStore APIKeyStore // The store of API keys used by automation clients
+	blobStore   BlobStore   // The store of uploaded images
+)
 
 func main() {
 	dbConnStr = os.Getenv("DATABASE_URL")
@@ -48,7 +49,7 @@ func main() {
 				Name:    "serve",
 				Aliases: []string{"s"},
 				Usage:   "serve administration website",
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:  "host",
 						Usage: "set the server host",
@@ -65,9 +66,225 @@ func main() {
 						Destination: &zolaPath,
 						Usage:       "Set the path where Zola files will be located",
 					},
-				},
+					&cli.StringFlag{
+						Name:  "store",
+						Value: "postgres",
+						Usage: "set the item store (postgres or memory)",
+					},
+					&cli.BoolFlag{
+						Name:  "no-auth",
+						Usage: "disable authentication, for local development only",
+					},
+					&cli.DurationFlag{
+						Name:  "session-lifetime",
+						Usage: "set how long users stay signed in",
+						Value: 24 * time.Hour,
+					},
+					&cli.Int64Flag{
+						Name:  "max-image-size",
+						Usage: "set the largest image that can be uploaded, in bytes",
+						Value: 10 << 20,
+					},
+					&cli.DurationFlag{
+						Name:  "gc-interval",
+						Usage: "remove unused images this often (default: never)",
+					},
+					&cli.DurationFlag{
+						Name:  "gc-grace",
+						Usage: "set how long unused images are kept after they were uploaded",
+						Value: 24 * time.Hour,
+					},
+					&cli.DurationFlag{
+						Name:  "gc-revision-retention",
+						Usage: "set how long images replaced by an edit are kept for rolling it back",
+						Value: 30 * 24 * time.Hour,
+					},
+					&cli.StringFlag{
+						Name:  "event-pages",
+						Usage: "generate one page per recurring event or one per occurrence (series or occurrence)",
+						Value: eventPagesSeries,
+					},
+					&cli.DurationFlag{
+						Name:  "occurrence-horizon",
+						Usage: "set how far ahead occurrences of recurring events are published",
+						Value: 90 * 24 * time.Hour,
+					},
+				}, blobStoreFlags...),
 				Action: serveAPI,
 			},
+			{
+				Name:  "purge",
+				Usage: "permanently remove items that have been in the trash for too long",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "retention",
+						Usage: "set how long deleted items are kept in the trash",
+						Value: 30 * 24 * time.Hour,
+					},
+				},
+				Action: purgeCommand,
+			},
+			{
+				Name:  "gc",
+				Usage: "remove data that is no longer used",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "images",
+						Usage: "remove stored images that no item refers to",
+						Flags: append([]cli.Flag{
+							&cli.DurationFlag{
+								Name:  "grace",
+								Usage: "keep unused images that were uploaded less than this long ago",
+								Value: 24 * time.Hour,
+							},
+							&cli.DurationFlag{
+								Name:  "revision-retention",
+								Usage: "keep images used by revisions made less than this long ago",
+								Value: 30 * 24 * time.Hour,
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "only list the images that would be removed",
+							},
+						}, blobStoreFlags...),
+						Action: gcImagesCommand,
+					},
+				},
+			},
+			{
+				Name:  "user",
+				Usage: "manage the accounts of the administration website",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     "add a new user",
+						ArgsUsage: "<username>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "role",
+								Usage: "set the role of the user (viewer, editor, publisher or admin)",
+								Value: "editor",
+							},
+							&cli.BoolFlag{
+								Name:  "password-stdin",
+								Usage: "read the password from stdin instead of asking for it",
+							},
+						},
+						Action: userAddCommand,
+					},
+					{
+						Name:   "list",
+						Usage:  "list all users",
+						Action: userListCommand,
+					},
+					{
+						Name:      "passwd",
+						Usage:     "change the password of a user",
+						ArgsUsage: "<username>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "password-stdin",
+								Usage: "read the password from stdin instead of asking for it",
+							},
+						},
+						Action: userPasswdCommand,
+					},
+					{
+						Name:      "remove",
+						Usage:     "remove a user",
+						ArgsUsage: "<username>",
+						Action:    userRemoveCommand,
+					},
+				},
+			},
+			{
+				Name:  "apikey",
+				Usage: "manage the API keys of automation clients",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "create",
+						Usage:     "create a new API key and print it once",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "types",
+								Usage: "limit the key to these item types (default: all types)",
+							},
+							&cli.StringSliceFlag{
+								Name:  "actions",
+								Usage: "allow the key these actions (read, write or generate)",
+								Value: cli.NewStringSlice(ActionRead),
+							},
+							&cli.DurationFlag{
+								Name:  "expires",
+								Usage: "let the key expire after this long (default: never)",
+							},
+						},
+						Action: apiKeyCreateCommand,
+					},
+					{
+						Name:   "list",
+						Usage:  "list all API keys",
+						Action: apiKeyListCommand,
+					},
+					{
+						Name:      "revoke",
+						Usage:     "revoke an API key",
+						ArgsUsage: "<prefix>",
+						Action:    apiKeyRevokeCommand,
+					},
+				},
+			},
+			{
+				Name:  "migrate",
+				Usage: "manage the database schema",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "up",
+						Usage:  "apply all pending migrations",
+						Action: migrateUpCommand,
+					},
+					{
+						Name:  "down",
+						Usage: "revert the most recently applied migrations",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "steps",
+								Usage: "set the number of migrations to revert",
+								Value: 1,
+							},
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "revert every applied migration",
+							},
+						},
+						Action: migrateDownCommand,
+					},
+					{
+						Name:   "status",
+						Usage:  "list migrations and whether they have been applied",
+						Action: migrateStatusCommand,
+					},
+				},
+			},
+			{
+				Name:  "hours",
+				Usage: "convert opening hours to and from the OpenStreetMap syntax",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "from-osm",
+						Usage:     "print OpenStreetMap opening hours as location JSON",
+						ArgsUsage: "<opening_hours>",
+						Action:    hoursFromOSMCommand,
+					},
+					{
+						Name:      "to-osm",
+						Usage:     "print the opening hours of location JSON in the OpenStreetMap syntax",
+						ArgsUsage: "[file]",
+						Action:    hoursToOSMCommand,
+					},
+				},
+			},
 		},
 	}
 
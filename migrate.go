@@ -0,0 +1,346 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single versioned schema change.
+// Migrations are stored as pairs of files named "<version>_<name>.up.sql"
+// and "<version>_<name>.down.sql" in the migrations directory.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a Migration has been applied to the database
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// loadMigrations reads the embedded migrations ordered by version
+func loadMigrations() (migrations []Migration, err error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		filename := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(filename, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(filename, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(filename, "."+direction+".sql")
+		tokens := strings.SplitN(base, "_", 2)
+		if len(tokens) < 2 {
+			err = fmt.Errorf("migration \"%s\" must be named <version>_<name>", filename)
+			return
+		}
+
+		var version int
+		if version, err = strconv.Atoi(tokens[0]); err != nil {
+			err = fmt.Errorf("migration \"%s\" has an invalid version", filename)
+			return
+		}
+
+		var data []byte
+		if data, err = migrationFiles.ReadFile(path.Join("migrations", filename)); err != nil {
+			return
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: tokens[1]}
+			byVersion[version] = m
+		} else if m.Name != tokens[1] {
+			err = fmt.Errorf("migration version %d is used by both \"%s\" and \"%s\"", version, m.Name, tokens[1])
+			return
+		}
+
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	for _, m := range byVersion {
+		if m.Up == "" {
+			err = fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+			return
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return
+}
+
+// ensureMigrationsTable creates the schema_migrations tracking table if it doesn't exist
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	return err
+}
+
+// migrationStatuses returns every known migration along with when it was applied
+func migrationStatuses(db *sql.DB) (statuses []MigrationStatus, err error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return
+	}
+
+	if err = ensureMigrationsTable(db); err != nil {
+		return
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, m := range migrations {
+		status := MigrationStatus{Migration: m}
+
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return
+}
+
+// runMigration executes a migration script and records the result in a single transaction
+func runMigration(db *sql.DB, m Migration, up bool) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// Serialize concurrent migration runs, e.g. several replicas starting at once
+	if _, err = tx.Exec("LOCK TABLE schema_migrations IN EXCLUSIVE MODE"); err != nil {
+		return
+	}
+
+	var exists bool
+	if err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", m.Version).Scan(&exists); err != nil {
+		return
+	}
+
+	if up {
+		if exists {
+			return
+		}
+
+		if _, err = tx.Exec(m.Up); err != nil {
+			return
+		}
+
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+		return
+	}
+
+	if !exists {
+		return
+	}
+
+	if m.Down == "" {
+		err = fmt.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+		return
+	}
+
+	if _, err = tx.Exec(m.Down); err != nil {
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", m.Version)
+	return
+}
+
+// migrateUp applies all pending migrations and returns the ones that were applied
+func migrateUp(db *sql.DB) (applied []Migration, err error) {
+	statuses, err := migrationStatuses(db)
+	if err != nil {
+		return
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+
+		if err = runMigration(db, status.Migration, true); err != nil {
+			err = fmt.Errorf("migration %d_%s: %w", status.Version, status.Name, err)
+			return
+		}
+
+		applied = append(applied, status.Migration)
+	}
+
+	return
+}
+
+// migrateDown reverts the most recently applied migrations, up to steps of them
+func migrateDown(db *sql.DB, steps int) (reverted []Migration, err error) {
+	statuses, err := migrationStatuses(db)
+	if err != nil {
+		return
+	}
+
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		status := statuses[i]
+		if status.AppliedAt == nil {
+			continue
+		}
+
+		if err = runMigration(db, status.Migration, false); err != nil {
+			err = fmt.Errorf("migration %d_%s: %w", status.Version, status.Name, err)
+			return
+		}
+
+		reverted = append(reverted, status.Migration)
+	}
+
+	return
+}
+
+// openMigrationDB opens a database connection for the migrate commands
+func openMigrationDB() (*sql.DB, error) {
+	if dbConnStr == "" {
+		return nil, errors.New("DATABASE_URL is not set")
+	}
+
+	return sql.Open("postgres", dbConnStr)
+}
+
+func migrateUpCommand(c *cli.Context) error {
+	db, err := openMigrationDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied, err := migrateUp(db)
+	for _, m := range applied {
+		log.Infof("Applied migration %d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Applied %d migration(s)\n", len(applied))
+	return nil
+}
+
+func migrateDownCommand(c *cli.Context) error {
+	db, err := openMigrationDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	steps := c.Int("steps")
+	if c.Bool("all") {
+		steps = int(^uint(0) >> 1)
+	}
+
+	reverted, err := migrateDown(db, steps)
+	for _, m := range reverted {
+		log.Infof("Reverted migration %d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Reverted %d migration(s)\n", len(reverted))
+	return nil
+}
+
+func migrateStatusCommand(c *cli.Context) error {
+	db, err := openMigrationDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statuses, err := migrationStatuses(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
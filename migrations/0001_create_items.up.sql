@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS items (
+    id         BIGSERIAL PRIMARY KEY,
+    data       JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS items_type_idx ON items ((data->>'type'));
@@ -0,0 +1,12 @@
+CREATE TABLE item_revisions (
+    item_id    BIGINT NOT NULL,
+    revision   INTEGER NOT NULL,
+    action     TEXT NOT NULL,
+    data       JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (item_id, revision)
+);
+
+-- Existing items start their history with their current data
+INSERT INTO item_revisions (item_id, revision, action, data, created_at)
+SELECT id, 1, 'create', data, updated_at FROM items;
@@ -0,0 +1,3 @@
+DELETE FROM items WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE items DROP COLUMN deleted_at;
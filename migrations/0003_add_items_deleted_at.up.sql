@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX items_deleted_at_idx ON items (deleted_at) WHERE deleted_at IS NOT NULL;
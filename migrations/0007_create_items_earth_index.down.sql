@@ -0,0 +1,2 @@
+-- cube and earthdistance are left installed, as other objects in the database may depend on them
+DROP INDEX IF EXISTS items_earth_idx;
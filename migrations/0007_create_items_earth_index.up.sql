@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS cube;
+CREATE EXTENSION IF NOT EXISTS earthdistance;
+
+CREATE INDEX IF NOT EXISTS items_earth_idx ON items
+    USING gist (ll_to_earth((data->'coordinates'->>0)::float8, (data->'coordinates'->>1)::float8))
+    WHERE jsonb_typeof(data->'coordinates'->0) = 'number' AND jsonb_typeof(data->'coordinates'->1) = 'number';
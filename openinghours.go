@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	LocationStatusOpen               = "open"                // the location keeps its opening hours
+	LocationStatusClosedIndefinitely = "closed-indefinitely" // the location is closed until further notice
+)
+
+const (
+	seasonDateLayout = "2006-01-02" // the format of seasons that happen once
+	seasonDayLayout  = "01-02"      // the format of seasons that repeat every year
+)
+
+// OpeningHoursException changes the opening hours of a location on a date, or from Date to EndDate, such as a holiday.
+// Hours are in the same format as the weekly opening hours, and are left empty when the location is closed.
+type OpeningHoursException struct {
+	Date    string `toml:"date" json:"date"`
+	EndDate string `toml:"end_date" json:"endDate,omitempty"`
+	Hours   string `toml:"hours" json:"hours,omitempty"`
+	Closed  bool   `toml:"closed" json:"closed,omitempty"`
+	Note    string `toml:"note" json:"note,omitempty"`
+}
+
+// SeasonalOpeningHours replaces the weekly opening hours of a location from From to To, inclusive.
+// Both are either dates like "2026-06-01", or days like "06-01" that repeat every year.
+// Repeating seasons may wrap around the new year, like "11-01" to "02-28".
+type SeasonalOpeningHours struct {
+	Name         string            `toml:"name" json:"name,omitempty"`
+	From         string            `toml:"from" json:"from"`
+	To           string            `toml:"to" json:"to"`
+	OpeningHours map[string]string `toml:"opening_hours" json:"openingHours"`
+}
+
+// ZolaOpeningHoursException is the format of OpeningHoursException used by Zola
+type ZolaOpeningHoursException struct {
+	Date    string                `toml:"date"`
+	EndDate string                `toml:"end_date"`
+	Closed  bool                  `toml:"closed"`
+	Hours   []LocationOpeningHour `toml:"hours"`
+	Note    string                `toml:"note"`
+}
+
+// ZolaSeasonalOpeningHours is the format of SeasonalOpeningHours used by Zola
+type ZolaSeasonalOpeningHours struct {
+	Name         string                           `toml:"name"`
+	From         string                           `toml:"from"`
+	To           string                           `toml:"to"`
+	Repeats      bool                             `toml:"repeats"`
+	OpeningHours map[string][]LocationOpeningHour `toml:"opening_hours"`
+}
+
+// parseExceptionDates parses the first and last day of an OpeningHoursException
+func parseExceptionDates(exception OpeningHoursException) (first, last time.Time, err error) {
+	if first, err = time.Parse(eventDateLayout, exception.Date); err != nil {
+		err = fmt.Errorf("date must be a date like %s", eventDateLayout)
+		return
+	}
+
+	last = first
+	if exception.EndDate != "" {
+		if last, err = time.Parse(eventDateLayout, exception.EndDate); err != nil {
+			err = fmt.Errorf("endDate must be a date like %s", eventDateLayout)
+			return
+		}
+
+		if last.Before(first) {
+			err = errors.New("endDate must not be before date")
+		}
+	}
+
+	return
+}
+
+// parseSeasonDay parses the start or end of a season.
+// year is 0 for seasons that repeat every year.
+func parseSeasonDay(s string) (year int, month time.Month, day int, err error) {
+	var t time.Time
+	if t, err = time.Parse(seasonDateLayout, s); err == nil {
+		return t.Year(), t.Month(), t.Day(), nil
+	}
+
+	// Year 0 is a leap year, so 02-29 is allowed
+	if t, err = time.Parse(seasonDayLayout, s); err == nil {
+		return 0, t.Month(), t.Day(), nil
+	}
+
+	err = fmt.Errorf("must be a date like %s, or a day like %s that repeats every year", seasonDateLayout, seasonDayLayout)
+	return
+}
+
+// checkSeason checks that a season starts and ends in the same format,
+// and that seasons that happen once don't end before they start
+func checkSeason(season SeasonalOpeningHours) (field string, err error) {
+	fromYear, fromMonth, fromDay, err := parseSeasonDay(season.From)
+	if err != nil {
+		return "from", err
+	}
+
+	toYear, toMonth, toDay, err := parseSeasonDay(season.To)
+	if err != nil {
+		return "to", err
+	}
+
+	if (fromYear == 0) != (toYear == 0) {
+		return "to", errors.New("must be in the same format as from")
+	}
+
+	from := time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.UTC)
+	to := time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.UTC)
+	if fromYear != 0 && to.Before(from) {
+		return "to", errors.New("must not be before from")
+	}
+
+	return
+}
+
+// Contains reports whether the season includes the date of day
+func (season *SeasonalOpeningHours) Contains(day time.Time) bool {
+	fromYear, fromMonth, fromDay, err := parseSeasonDay(season.From)
+	if err != nil {
+		return false
+	}
+
+	toYear, toMonth, toDay, err := parseSeasonDay(season.To)
+	if err != nil {
+		return false
+	}
+
+	if fromYear != 0 {
+		date := civilDate(day)
+		return !date.Before(time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.UTC)) &&
+			!date.After(time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.UTC))
+	}
+
+	// Compare days of the year as month*100+day, so that leap years don't matter
+	d := int(day.Month())*100 + day.Day()
+	from := int(fromMonth)*100 + fromDay
+	to := int(toMonth)*100 + toDay
+	if from <= to {
+		return d >= from && d <= to
+	}
+	return d >= from || d <= to
+}
+
+// Contains reports whether the exception applies on the date of day
+func (exception *OpeningHoursException) Contains(day time.Time) bool {
+	first, last, err := parseExceptionDates(*exception)
+	if err != nil {
+		return false
+	}
+
+	date := civilDate(day)
+	return !date.Before(first) && !date.After(last)
+}
+
+// zolaWeeklyOpeningHours parses weekly opening hours for Zola
+func zolaWeeklyOpeningHours(openingHours map[string]string) (m map[string][]LocationOpeningHour, err error) {
+	m = make(map[string][]LocationOpeningHour)
+
+	for k, v := range openingHours {
+		if m[k], err = parseOpeningHours(v); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// ZolaOpeningHoursExceptions converts the exceptions to the opening hours for Zola, ordered by date
+func (location *Location) ZolaOpeningHoursExceptions() (exceptions []ZolaOpeningHoursException, err error) {
+	for _, exception := range location.OpeningHoursExceptions {
+		zolaException := ZolaOpeningHoursException{
+			Date:    exception.Date,
+			EndDate: exception.EndDate,
+			Closed:  exception.Closed || exception.Hours == "",
+			Note:    exception.Note,
+		}
+
+		if zolaException.EndDate == "" {
+			zolaException.EndDate = exception.Date
+		}
+
+		if !zolaException.Closed {
+			if zolaException.Hours, err = parseOpeningHours(exception.Hours); err != nil {
+				return
+			}
+		}
+
+		exceptions = append(exceptions, zolaException)
+	}
+
+	sort.SliceStable(exceptions, func(i, j int) bool { return exceptions[i].Date < exceptions[j].Date })
+	return
+}
+
+// ZolaSeasonalOpeningHours converts the seasonal opening hours for Zola
+func (location *Location) ZolaSeasonalOpeningHours() (seasons []ZolaSeasonalOpeningHours, err error) {
+	for _, season := range location.SeasonalOpeningHours {
+		zolaSeason := ZolaSeasonalOpeningHours{
+			Name:    season.Name,
+			From:    season.From,
+			To:      season.To,
+			Repeats: len(season.From) == len(seasonDayLayout),
+		}
+
+		if zolaSeason.OpeningHours, err = zolaWeeklyOpeningHours(season.OpeningHours); err != nil {
+			return
+		}
+
+		seasons = append(seasons, zolaSeason)
+	}
+
+	return
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeasonContains(t *testing.T) {
+	day := func(s string) time.Time {
+		t, _ := time.Parse(eventDateLayout, s)
+		return t
+	}
+
+	winter := SeasonalOpeningHours{From: "11-01", To: "02-29"}
+	assert.True(t, winter.Contains(day("2026-12-24")))
+	assert.True(t, winter.Contains(day("2027-02-28")))
+	assert.False(t, winter.Contains(day("2027-03-01")))
+
+	summer := SeasonalOpeningHours{From: "2026-06-01", To: "2026-08-31"}
+	assert.True(t, summer.Contains(day("2026-06-01")))
+	assert.False(t, summer.Contains(day("2027-07-01")))
+
+	christmas := OpeningHoursException{Date: "2026-12-24", EndDate: "2026-12-26", Closed: true}
+	assert.True(t, christmas.Contains(day("2026-12-26")))
+	assert.False(t, christmas.Contains(day("2026-12-27")))
+}
+
+func TestLocationZolaOpeningHours(t *testing.T) {
+	blobStore = NewFSBlobStore(t.TempDir())
+	cover := testJPEG(t, 8, 8)
+	blobStore.Put(BlobInfo{Key: "cover", Size: int64(len(cover)), ContentType: "image/jpeg"}, bytes.NewReader(cover))
+
+	location := Location{
+		CoverImageURL: "cover",
+		Status:        LocationStatusClosedIndefinitely,
+		TimeZone:      "UTC",
+		OpeningHours:  map[string]string{"mon": "9-17"},
+		OpeningHoursExceptions: []OpeningHoursException{
+			{Date: "2026-12-31", Hours: "10-14"},
+			{Date: "2026-12-25", Closed: true, Note: "Christmas"},
+		},
+		SeasonalOpeningHours: []SeasonalOpeningHours{{Name: "Summer", From: "06-01", To: "08-31", OpeningHours: map[string]string{"mon": "9-21"}}},
+	}
+
+	zolaLocation, err := location.Zola(time.Date(2026, 12, 24, 12, 0, 0, 0, time.UTC))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, LocationStatusClosedIndefinitely, zolaLocation.Extra.Status)
+	assert.Equal(t, []ZolaOpeningHoursException{
+		{Date: "2026-12-25", EndDate: "2026-12-25", Closed: true, Note: "Christmas"},
+		{Date: "2026-12-31", EndDate: "2026-12-31", Hours: []LocationOpeningHour{{Start: []int{10, 0}, End: []int{14, 0}}}},
+	}, zolaLocation.Extra.OpeningHoursExceptions)
+	assert.True(t, zolaLocation.Extra.SeasonalOpeningHours[0].Repeats)
+
+	// The upcoming week starts on the day the site is generated, and nothing opens while closed indefinitely
+	assert.Equal(t, ZolaOpeningDay{Day: "friday", Date: "2026-12-25", Closed: true}, zolaLocation.Extra.UpcomingHours[1])
+	assert.Equal(t, []int{21, 0}, zolaLocation.Extra.SeasonalOpeningHours[0].OpeningHours["mon"][0].End)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// openStatusHorizon is how many days ahead the next opening or closing of a location is looked for
+const openStatusHorizon = 366
+
+// OpeningInterval is a time during which a location is open, from Start until End
+type OpeningInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// OpenStatus tells whether a location is open at an instant.
+// OpensAt is the next opening when the location is closed, and ClosesAt the next closing when it is open.
+// Either is nil when it doesn't happen within a year.
+// Today lists the times the location is open on the day of the instant, including hours that spill over from the day before.
+type OpenStatus struct {
+	Open     bool              `json:"open"`
+	OpensAt  *time.Time        `json:"opensAt,omitempty"`
+	ClosesAt *time.Time        `json:"closesAt,omitempty"`
+	Today    []OpeningInterval `json:"today"`
+}
+
+// ZolaOpeningDay is the opening hours of a single day, used by Zola to render tables of opening hours.
+// Date is left empty in the regular weekly table.
+type ZolaOpeningDay struct {
+	Day    string                `toml:"day"`
+	Date   string                `toml:"date"`
+	Closed bool                  `toml:"closed"`
+	Hours  []LocationOpeningHour `toml:"hours"`
+	Note   string                `toml:"note"`
+}
+
+// weeklyHours returns the opening hours of a weekday in weekly opening hours
+func weeklyHours(openingHours map[string]string, weekday time.Weekday) (hours []LocationOpeningHour, err error) {
+	days := make([]string, 0, len(openingHours))
+	for day := range openingHours {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	// A day may be given more than once, like "mon" and "monday"
+	for _, day := range days {
+		var d time.Weekday
+		if d, err = parseWeekday(day); err != nil {
+			return
+		} else if d != weekday {
+			continue
+		}
+
+		var dayHours []LocationOpeningHour
+		if dayHours, err = parseOpeningHours(openingHours[day]); err != nil {
+			return
+		}
+		hours = append(hours, dayHours...)
+	}
+
+	return
+}
+
+// hoursOn returns the opening hours of the location on the date of day: those of an exception for the date,
+// or else those of the season that includes the date, or else the weekly opening hours.
+// Public holidays aren't known, so PublicHolidayHours are not taken into account.
+func (location *Location) hoursOn(day time.Time) (hours []LocationOpeningHour, note string, err error) {
+	if location.Status == LocationStatusClosedIndefinitely {
+		return
+	}
+
+	for _, exception := range location.OpeningHoursExceptions {
+		if exception.Contains(day) {
+			if !exception.Closed && exception.Hours != "" {
+				hours, err = parseOpeningHours(exception.Hours)
+			}
+			return hours, exception.Note, err
+		}
+	}
+
+	for _, season := range location.SeasonalOpeningHours {
+		if season.Contains(day) {
+			hours, err = weeklyHours(season.OpeningHours, day.Weekday())
+			return
+		}
+	}
+
+	hours, err = weeklyHours(location.OpeningHours, day.Weekday())
+	return
+}
+
+// intervalsOn returns the times the location opens on the date of midnight, which is a midnight in loc.
+// Hours past 24 end on the next day.
+func (location *Location) intervalsOn(midnight time.Time, loc *time.Location) (intervals []OpeningInterval, err error) {
+	hours, _, err := location.hoursOn(midnight)
+	if err != nil {
+		return
+	}
+
+	at := func(t []int) time.Time {
+		minute := 0
+		if len(t) > 1 {
+			minute = t[1]
+		}
+		return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), t[0], minute, 0, 0, loc)
+	}
+
+	for _, h := range hours {
+		if interval := (OpeningInterval{at(h.Start), at(h.End)}); interval.End.After(interval.Start) {
+			intervals = append(intervals, interval)
+		}
+	}
+
+	return
+}
+
+// mergeIntervals sorts intervals and joins the ones that overlap or touch
+func mergeIntervals(intervals []OpeningInterval) (merged []OpeningInterval) {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+
+	for _, interval := range intervals {
+		if n := len(merged); n > 0 && !interval.Start.After(merged[n-1].End) {
+			if interval.End.After(merged[n-1].End) {
+				merged[n-1].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	return
+}
+
+// OpenStatus tells whether the location is open at the instant at, with its opening hours taken in loc.
+// Hours that run past midnight keep the location open on the next day, and back-to-back hours
+// across midnight, like "18-24" followed by "0-2", count as a single opening.
+func (location *Location) OpenStatus(at time.Time, loc *time.Location) (status OpenStatus, err error) {
+	status.Today = make([]OpeningInterval, 0)
+	if location.Status == LocationStatusClosedIndefinitely {
+		return
+	}
+
+	at = at.In(loc)
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	var intervals []OpeningInterval
+	decided := false
+
+	// Start from yesterday for the hours that spill over into today, and look further ahead
+	// until a later day can no longer change when the location next opens or closes
+	for i := -1; i <= openStatusHorizon && !decided; i++ {
+		var dayIntervals []OpeningInterval
+		if dayIntervals, err = location.intervalsOn(today.AddDate(0, 0, i), loc); err != nil {
+			return
+		}
+		intervals = mergeIntervals(append(intervals, dayIntervals...))
+
+		if i < 1 {
+			continue
+		}
+
+		status = OpenStatus{}
+		nextDay := today.AddDate(0, 0, i+1)
+		for _, interval := range intervals {
+			if !interval.Start.After(at) && interval.End.After(at) {
+				status.Open = true
+				if interval.End.Before(nextDay) {
+					closesAt := interval.End
+					status.ClosesAt = &closesAt
+					decided = true
+				}
+				break
+			} else if interval.Start.After(at) {
+				opensAt := interval.Start
+				status.OpensAt = &opensAt
+				decided = true
+				break
+			}
+		}
+	}
+
+	status.Today = make([]OpeningInterval, 0)
+	for _, interval := range intervals {
+		if interval.Start.Before(tomorrow) && interval.End.After(today) {
+			status.Today = append(status.Today, interval)
+		}
+	}
+
+	return
+}
+
+// ZolaWeeklyTable lists the weekly opening hours of the location from Monday to Sunday
+func (location *Location) ZolaWeeklyTable() (table []ZolaOpeningDay, err error) {
+	for i := 0; i < 7; i++ {
+		weekday := osmWeekday(i)
+
+		day := ZolaOpeningDay{Day: strings.ToLower(weekday.String())}
+		if day.Hours, err = weeklyHours(location.OpeningHours, weekday); err != nil {
+			return
+		}
+		day.Closed = len(day.Hours) == 0
+
+		table = append(table, day)
+	}
+
+	return
+}
+
+// ZolaUpcomingTable lists the opening hours of the location on the seven days starting on the date of from,
+// with its exceptions and seasons applied
+func (location *Location) ZolaUpcomingTable(from time.Time) (table []ZolaOpeningDay, err error) {
+	for i := 0; i < 7; i++ {
+		date := from.AddDate(0, 0, i)
+
+		day := ZolaOpeningDay{Day: strings.ToLower(date.Weekday().String()), Date: date.Format(eventDateLayout)}
+		if day.Hours, day.Note, err = location.hoursOn(date); err != nil {
+			return
+		}
+		day.Closed = len(day.Hours) == 0
+
+		table = append(table, day)
+	}
+
+	return
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationOpenStatus(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Singapore")
+	at := func(s string) time.Time {
+		t, _ := time.ParseInLocation(eventTimeLayout, s, loc)
+		return t
+	}
+
+	location := Location{
+		OpeningHours: map[string]string{"monday": "9-17", "friday": "18-26", "saturday": "1-3,12-14"},
+		OpeningHoursExceptions: []OpeningHoursException{
+			{Date: "2026-10-26", Closed: true},
+		},
+	}
+
+	status, err := location.OpenStatus(at("2026-10-19T10:00"), loc)
+	assert.NoError(t, err)
+	assert.True(t, status.Open)
+	assert.Equal(t, at("2026-10-19T17:00"), *status.ClosesAt)
+	assert.Equal(t, []OpeningInterval{{at("2026-10-19T09:00"), at("2026-10-19T17:00")}}, status.Today)
+
+	status, err = location.OpenStatus(at("2026-10-19T18:00"), loc)
+	assert.NoError(t, err)
+	assert.False(t, status.Open)
+	assert.Equal(t, at("2026-10-23T18:00"), *status.OpensAt)
+
+	// Friday's hours run into Saturday's, so the location stays open until 3 AM
+	status, err = location.OpenStatus(at("2026-10-24T01:30"), loc)
+	assert.NoError(t, err)
+	assert.True(t, status.Open)
+	assert.Equal(t, at("2026-10-24T03:00"), *status.ClosesAt)
+	assert.Equal(t, []OpeningInterval{
+		{at("2026-10-23T18:00"), at("2026-10-24T03:00")},
+		{at("2026-10-24T12:00"), at("2026-10-24T14:00")},
+	}, status.Today)
+
+	status, err = location.OpenStatus(at("2026-10-25T20:00"), loc)
+	assert.NoError(t, err)
+	assert.Equal(t, at("2026-10-30T18:00"), *status.OpensAt)
+
+	location.Status = LocationStatusClosedIndefinitely
+	status, err = location.OpenStatus(at("2026-10-19T10:00"), loc)
+	assert.NoError(t, err)
+	assert.False(t, status.Open)
+	assert.Nil(t, status.OpensAt)
+
+	allDay := Location{OpeningHours: map[string]string{"mon": "0-24", "tue": "0-24", "wed": "0-24", "thu": "0-24", "fri": "0-24", "sat": "0-24", "sun": "0-24"}}
+	status, err = allDay.OpenStatus(at("2026-10-19T10:00"), loc)
+	assert.NoError(t, err)
+	assert.True(t, status.Open)
+	assert.Nil(t, status.ClosesAt)
+}
+
+func TestGetLocationsOpen(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	bakery := `{"type": "location", "title": "Bakery", "coverImageURL": "abc", "openingHours": {"monday": "7-15"}}`
+	bar := `{"type": "location", "title": "Bar", "coverImageURL": "abc", "openingHours": {"sunday": "20-26"}}`
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, bakery).Code)
+	assert.Equal(t, 201, testRequest(r, "POST", "/item", editor, bar).Code)
+
+	// Stored before opening hours were validated
+	_, err := itemStore.Create([]byte(`{"type": "location", "title": "Pub", "openingHours": {"someday": "late"}}`))
+	assert.NoError(t, err)
+
+	w := testRequest(r, "GET", "/locations/open?at=2026-10-18T17:30:00Z&tz=Asia/Singapore", editor, "")
+	if !assert.Equal(t, 200, w.Code) {
+		return
+	}
+
+	var response struct {
+		Locations []LocationOpenStatus `json:"locations"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	open := make(map[string]bool)
+	for _, location := range response.Locations {
+		if location.Error != "" {
+			assert.Equal(t, "Pub", location.Title)
+			assert.Nil(t, location.OpenStatus)
+			continue
+		}
+		open[location.Title] = location.Open
+	}
+	assert.Equal(t, map[string]bool{"Bakery": false, "Bar": true}, open)
+	assert.Len(t, response.Locations, 3)
+
+	assert.Equal(t, 400, testRequest(r, "GET", "/locations/open?tz=Mars/Olympus", editor, "").Code)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// publicHolidaysClosed is the value of Location.PublicHolidayHours for locations that close on public holidays
+const publicHolidaysClosed = "closed"
+
+// osmWeekdays lists the OpenStreetMap abbreviations of the days of the week, starting on Monday
+var osmWeekdays = []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"}
+
+// osmWeekdayIndex returns the position of an OpenStreetMap day abbreviation in osmWeekdays
+func osmWeekdayIndex(s string) (int, bool) {
+	for i, day := range osmWeekdays {
+		if s == day {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// osmWeekday converts a position in osmWeekdays into a time.Weekday
+func osmWeekday(i int) time.Weekday {
+	return time.Weekday((i + 1) % 7)
+}
+
+// parseOSMOpeningHours parses a practical subset of the OpenStreetMap opening_hours syntax,
+// such as "Mo-Fr 09:00-17:30; Sa 10:00-14:00; PH off", into weekly opening hours in the format of
+// Location.OpeningHours and the hours of public holidays in the format of Location.PublicHolidayHours.
+//
+// Rules are separated by ";", and later rules replace earlier ones for the days they select.
+// A rule selects days of the week, ranges of them or public holidays ("PH"), or every day when it has no selector,
+// and either opens at one or more times or closes with "off" or "closed". "24/7" opens every day all day.
+func parseOSMOpeningHours(s string) (openingHours map[string]string, publicHolidays string, err error) {
+	var days [7][]LocationOpeningHour
+	var open [7]bool
+	var holidays []LocationOpeningHour
+	hasHolidays := false
+
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		if rule == "24/7" {
+			for i := range days {
+				days[i] = []LocationOpeningHour{{Start: []int{0, 0}, End: []int{24, 0}}}
+				open[i] = true
+			}
+			continue
+		}
+
+		selector, hours := "", rule
+		if i := strings.IndexByte(rule, ' '); i >= 0 && !isOSMTimes(rule[:i]) {
+			selector, hours = rule[:i], strings.TrimSpace(rule[i+1:])
+		}
+
+		var selected [7]bool
+		selectsHolidays := false
+
+		if selector == "" {
+			selected = [7]bool{true, true, true, true, true, true, true}
+		} else if selected, selectsHolidays, err = parseOSMSelector(selector); err != nil {
+			return
+		}
+
+		var ranges []LocationOpeningHour
+		if hours != "off" && hours != "closed" {
+			if ranges, err = parseOSMTimes(hours); err != nil {
+				return
+			}
+		}
+
+		for i := range days {
+			if selected[i] {
+				days[i] = ranges
+				open[i] = len(ranges) > 0
+			}
+		}
+
+		if selectsHolidays {
+			holidays = ranges
+			hasHolidays = true
+		}
+	}
+
+	openingHours = make(map[string]string)
+	for i := range days {
+		if open[i] {
+			openingHours[strings.ToLower(osmWeekday(i).String())] = formatOpeningHours(days[i])
+		}
+	}
+
+	if hasHolidays {
+		publicHolidays = publicHolidaysClosed
+		if len(holidays) > 0 {
+			publicHolidays = formatOpeningHours(holidays)
+		}
+	}
+
+	return
+}
+
+// parseOSMSelector parses a comma separated list of days of the week, ranges of them and "PH"
+func parseOSMSelector(s string) (selected [7]bool, holidays bool, err error) {
+	for _, part := range strings.Split(s, ",") {
+		if part == "PH" {
+			holidays = true
+			continue
+		}
+
+		bounds := strings.Split(part, "-")
+		first, ok := osmWeekdayIndex(bounds[0])
+		last := first
+		if ok && len(bounds) == 2 {
+			last, ok = osmWeekdayIndex(bounds[1])
+		}
+
+		if !ok || len(bounds) > 2 {
+			err = fmt.Errorf("\"%s\" is not supported, only days of the week like Mo-Fr and PH are", part)
+			return
+		}
+
+		// Ranges like Sa-Mo wrap around the end of the week
+		for i := first; ; i = (i + 1) % 7 {
+			selected[i] = true
+			if i == last {
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// isOSMTimes reports whether s starts with a time rather than a selector
+func isOSMTimes(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// parseOSMTimes parses a comma separated list of time ranges like "09:00-12:00,13:00-17:30".
+// Ranges that end before they start end on the next day.
+func parseOSMTimes(s string) (ranges []LocationOpeningHour, err error) {
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+
+		bounds := strings.Split(part, "-")
+		if len(bounds) != 2 {
+			err = fmt.Errorf("\"%s\" is not a time range like 09:00-17:30", part)
+			return
+		}
+
+		var start, end []int
+		if start, err = parseOSMTime(bounds[0], 24); err != nil {
+			return
+		}
+		if end, err = parseOSMTime(bounds[1], 48); err != nil {
+			return
+		}
+
+		if end[0]*60+end[1] <= start[0]*60+start[1] {
+			end[0] += 24
+		}
+
+		ranges = append(ranges, LocationOpeningHour{Start: start, End: end})
+	}
+
+	return
+}
+
+// parseOSMTime parses a time like "09:30" whose hour is at most maxHour
+func parseOSMTime(s string, maxHour int) ([]int, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 1 || len(s)-i != 3 {
+		return nil, fmt.Errorf("\"%s\" is not a time like 09:30", s)
+	}
+
+	hour, err := strconv.Atoi(s[:i])
+	if err != nil || hour < 0 || hour > maxHour {
+		return nil, fmt.Errorf("\"%s\" is not a time like 09:30", s)
+	}
+
+	minute, err := strconv.Atoi(s[i+1:])
+	if err != nil || minute < 0 || minute > 59 || (hour == maxHour && minute > 0) {
+		return nil, fmt.Errorf("\"%s\" is not a time like 09:30", s)
+	}
+
+	return []int{hour, minute}, nil
+}
+
+// expandOSMOpeningHours replaces the "openingHoursOSM" field of valid item data
+// with the "openingHours" and "publicHolidayHours" it stands for
+func expandOSMOpeningHours(data map[string]interface{}) {
+	s, ok := data["openingHoursOSM"].(string)
+	if !ok {
+		return
+	}
+
+	openingHours, publicHolidays, err := parseOSMOpeningHours(s)
+	if err != nil {
+		return
+	}
+
+	m := make(map[string]interface{}, len(openingHours))
+	for day, hours := range openingHours {
+		m[day] = hours
+	}
+	data["openingHours"] = m
+
+	if publicHolidays != "" {
+		data["publicHolidayHours"] = publicHolidays
+	} else {
+		delete(data, "publicHolidayHours")
+	}
+
+	delete(data, "openingHoursOSM")
+}
+
+// formatOpeningHours formats opening hour ranges in the format understood by parseOpeningHours
+func formatOpeningHours(ranges []LocationOpeningHour) string {
+	formatTime := func(t []int) string {
+		if len(t) > 1 && t[1] != 0 {
+			return fmt.Sprintf("%d.%02d", t[0], t[1])
+		}
+		return strconv.Itoa(t[0])
+	}
+
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		parts = append(parts, formatTime(r.Start)+"-"+formatTime(r.End))
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatOSMOpeningHours formats weekly opening hours in the format of Location.OpeningHours,
+// and the hours of public holidays in the format of Location.PublicHolidayHours, in the OpenStreetMap syntax.
+// Days with the same hours are grouped, like "Mo-Fr 09:00-17:30; Sa 10:00-14:00; PH off".
+func formatOSMOpeningHours(openingHours map[string]string, publicHolidays string) (string, error) {
+	var days [7]string
+
+	for day, hours := range openingHours {
+		weekday, err := parseWeekday(day)
+		if err != nil {
+			return "", err
+		}
+
+		ranges, err := parseOpeningHours(hours)
+		if err != nil {
+			return "", err
+		}
+
+		days[(int(weekday)+6)%7] = formatOSMTimes(ranges)
+	}
+
+	var rules []string
+	if days == [7]string{"00:00-24:00", "00:00-24:00", "00:00-24:00", "00:00-24:00", "00:00-24:00", "00:00-24:00", "00:00-24:00"} {
+		rules = append(rules, "24/7")
+	} else {
+		// Group the days by their hours, in the order of the first day of each group
+		var groups []string
+		selectors := make(map[string][]int)
+		for i, times := range days {
+			if times == "" {
+				continue
+			}
+
+			if _, ok := selectors[times]; !ok {
+				groups = append(groups, times)
+			}
+			selectors[times] = append(selectors[times], i)
+		}
+
+		for _, times := range groups {
+			rules = append(rules, formatOSMDays(selectors[times])+" "+times)
+		}
+	}
+
+	switch publicHolidays {
+	case "":
+	case publicHolidaysClosed:
+		rules = append(rules, "PH off")
+	default:
+		ranges, err := parseOpeningHours(publicHolidays)
+		if err != nil {
+			return "", err
+		}
+		rules = append(rules, "PH "+formatOSMTimes(ranges))
+	}
+
+	return strings.Join(rules, "; "), nil
+}
+
+// formatOSMDays formats positions in osmWeekdays, joining consecutive days into ranges like "Mo-Fr"
+func formatOSMDays(days []int) string {
+	sort.Ints(days)
+
+	var parts []string
+	for i := 0; i < len(days); {
+		j := i
+		for j+1 < len(days) && days[j+1] == days[j]+1 {
+			j++
+		}
+
+		switch j - i {
+		case 0:
+			parts = append(parts, osmWeekdays[days[i]])
+		case 1:
+			parts = append(parts, osmWeekdays[days[i]], osmWeekdays[days[j]])
+		default:
+			parts = append(parts, osmWeekdays[days[i]]+"-"+osmWeekdays[days[j]])
+		}
+
+		i = j + 1
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// formatOSMTimes formats opening hour ranges like "09:00-12:00,13:00-17:30".
+// Ranges that end after midnight are written with the time on the next day, like "18:00-02:00".
+func formatOSMTimes(ranges []LocationOpeningHour) string {
+	parts := make([]string, 0, len(ranges))
+
+	for _, r := range ranges {
+		start := []int{r.Start[0], 0}
+		end := []int{r.End[0], 0}
+		if len(r.Start) > 1 {
+			start[1] = r.Start[1]
+		}
+		if len(r.End) > 1 {
+			end[1] = r.End[1]
+		}
+
+		if end[0]*60+end[1] > 24*60 {
+			end[0] -= 24
+		}
+
+		parts = append(parts, fmt.Sprintf("%02d:%02d-%02d:%02d", start[0], start[1], end[0], end[1]))
+	}
+
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOSMOpeningHours(t *testing.T) {
+	openingHours, publicHolidays, err := parseOSMOpeningHours("Mo-Fr 09:00-17:30; Sa 10:00-12:00, 13:00-16:00; We off; Su-Mo 18:00-02:00; PH off")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, map[string]string{
+		"monday":   "18-26",
+		"tuesday":  "9-17.30",
+		"thursday": "9-17.30",
+		"friday":   "9-17.30",
+		"saturday": "10-12,13-16",
+		"sunday":   "18-26",
+	}, openingHours)
+	assert.Equal(t, publicHolidaysClosed, publicHolidays)
+
+	openingHours, publicHolidays, err = parseOSMOpeningHours("24/7; PH 10:00-14:00")
+	assert.NoError(t, err)
+	assert.Len(t, openingHours, 7)
+	assert.Equal(t, "0-24", openingHours["wednesday"])
+	assert.Equal(t, "10-14", publicHolidays)
+
+	for _, s := range []string{"Mo-Fr 9-17", "Jan-Mar 09:00-17:00", "Mo 09:00-17:60", "Mo-Fr 09:00-17:00 \"by appointment\""} {
+		_, _, err := parseOSMOpeningHours(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFormatOSMOpeningHours(t *testing.T) {
+	s, err := formatOSMOpeningHours(map[string]string{
+		"mon": "9-17.30",
+		"tue": "9-17.30",
+		"wed": "9-17.30",
+		"fri": "9-17.30",
+		"sat": "10-12,13-16",
+		"sun": "18-26",
+	}, publicHolidaysClosed)
+	assert.NoError(t, err)
+	assert.Equal(t, "Mo-We,Fr 09:00-17:30; Sa 10:00-12:00,13:00-16:00; Su 18:00-02:00; PH off", s)
+
+	for _, s := range []string{"Mo-Fr 09:00-17:30; Sa 10:00-14:00; PH off", "24/7", "Mo,Tu 08:00-24:00; PH 10:00-14:00"} {
+		openingHours, publicHolidays, err := parseOSMOpeningHours(s)
+		assert.NoError(t, err)
+
+		formatted, err := formatOSMOpeningHours(openingHours, publicHolidays)
+		assert.NoError(t, err)
+		assert.Equal(t, s, formatted)
+	}
+}
+
+func TestPostItemOpeningHoursOSM(t *testing.T) {
+	r := newTestRouter(t)
+	editor := testLogin(t, r, "eddie", RoleEditor)
+
+	w := testRequest(r, "POST", "/item", editor, `{"type": "location", "title": "Cafe", "coverImageURL": "abc",
+		"openingHours": {"sunday": "9-12"}, "openingHoursOSM": "Mo-Fr 08:00-18:00; PH off"}`)
+	if !assert.Equal(t, 201, w.Code) {
+		return
+	}
+
+	items, err := itemStore.ListByType("location")
+	if !assert.NoError(t, err) || !assert.Len(t, items, 1) {
+		return
+	}
+
+	location, err := LocationFromItem(items[0])
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, location.OpeningHours, 5)
+	assert.Equal(t, "8-18", location.OpeningHours["monday"])
+	assert.Equal(t, publicHolidaysClosed, location.PublicHolidayHours)
+	assert.NotContains(t, string(items[0].Data), "openingHoursOSM")
+
+	w = testRequest(r, "POST", "/item", editor, `{"type": "location", "title": "Bar", "coverImageURL": "abc", "openingHoursOSM": "Mo-Fr sunset-late"}`)
+	assert.Equal(t, 422, w.Code)
+	assert.Contains(t, w.Body.String(), "openingHoursOSM")
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultItemQuerySize = 10
+	maxItemQuerySize     = 100
+
+	defaultNearbyRadius = 5000   // meters
+	maxNearbyRadius     = 200000 // meters
+	defaultNearbySize   = 100
+	maxNearbySize       = 1000
+)
+
+// ErrInvalidCursor is returned when a cursor token can't be decoded or doesn't match the query
+var ErrInvalidCursor = errors.New("cursor is not valid")
+
+// ItemQuery describes which items to list and in which order
+type ItemQuery struct {
+	Type   string   // only return items of this type
+	Types  []string // only return items of one of these types, if not empty
+	Tag    string   // only return items that have this tag
+	Q      string   // only return items whose title or description contains this text
+	Sort   string   // created_at, updated_at or title
+	Order  string   // asc or desc
+	Size   int
+	Cursor *ItemCursor // continue after the item described by the cursor
+}
+
+// NearbyQuery describes the items to find around a point
+type NearbyQuery struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64  // in meters
+	Type      string   // only return items of this type
+	Types     []string // only return items of one of these types, if not empty
+	Size      int
+}
+
+// NearbyItem is an item found by a NearbyQuery, with its distance in meters from the point of the query
+type NearbyItem struct {
+	Item
+	Distance float64
+}
+
+// ItemPage is a single page of results of an ItemQuery
+type ItemPage struct {
+	Items      []Item
+	NextCursor *ItemCursor // nil when there are no more results
+	Total      int         // number of items matching the query regardless of paging
+}
+
+// ItemCursor points at the last item of a page.
+// Value holds the sort key of that item, and ID breaks ties between equal keys.
+type ItemCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// Encode turns the cursor into an opaque token that is safe to put in a URL
+func (cursor *ItemCursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeItemCursor parses a token created by ItemCursor.Encode
+func decodeItemCursor(token string) (cursor *ItemCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		err = ErrInvalidCursor
+		return
+	}
+
+	cursor = &ItemCursor{}
+	if err = json.Unmarshal(data, cursor); err != nil {
+		err = ErrInvalidCursor
+	}
+
+	return
+}
+
+// parseItemQuery reads an ItemQuery from the query string of a request
+func parseItemQuery(c *gin.Context) (query ItemQuery, err error) {
+	query.Type = c.Query("type")
+	query.Tag = c.Query("tag")
+	query.Q = strings.TrimSpace(c.Query("q"))
+	query.Sort = c.DefaultQuery("sort", "created_at")
+	query.Order = strings.ToLower(c.DefaultQuery("order", "desc"))
+	query.Size = defaultItemQuerySize
+
+	switch query.Sort {
+	case "created_at", "updated_at", "title":
+	default:
+		err = fmt.Errorf("sort must be one of created_at, updated_at or title")
+		return
+	}
+
+	if query.Order != "asc" && query.Order != "desc" {
+		err = fmt.Errorf("order must be either asc or desc")
+		return
+	}
+
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		if query.Size, err = strconv.Atoi(sizeStr); err != nil {
+			err = fmt.Errorf("size is not a valid number")
+			return
+		}
+	}
+
+	if query.Size < 1 {
+		query.Size = 1
+	} else if query.Size > maxItemQuerySize {
+		query.Size = maxItemQuerySize
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		if query.Cursor, err = decodeItemCursor(token); err != nil {
+			return
+		}
+
+		// A cursor only makes sense for the ordering it was created with
+		if query.Cursor.Sort != query.Sort || query.Cursor.Order != query.Order {
+			err = ErrInvalidCursor
+			return
+		}
+	}
+
+	return
+}
+
+// parseNearbyQuery reads a NearbyQuery from the query string of a request
+func parseNearbyQuery(c *gin.Context) (query NearbyQuery, err error) {
+	query.Type = c.Query("type")
+	query.Radius = defaultNearbyRadius
+	query.Size = defaultNearbySize
+
+	// ParseFloat accepts "NaN", which fails every comparison, so it is ruled out explicitly
+	if query.Latitude, err = strconv.ParseFloat(c.Query("lat"), 64); err != nil || math.IsNaN(query.Latitude) || query.Latitude < -90 || query.Latitude > 90 {
+		err = fmt.Errorf("lat must be a latitude between -90 and 90")
+		return
+	}
+
+	if query.Longitude, err = strconv.ParseFloat(c.Query("lng"), 64); err != nil || math.IsNaN(query.Longitude) || query.Longitude < -180 || query.Longitude > 180 {
+		err = fmt.Errorf("lng must be a longitude between -180 and 180")
+		return
+	}
+
+	if radiusStr := c.Query("radius"); radiusStr != "" {
+		if query.Radius, err = strconv.ParseFloat(radiusStr, 64); err != nil || math.IsNaN(query.Radius) || math.IsInf(query.Radius, 0) || query.Radius <= 0 || query.Radius > maxNearbyRadius {
+			err = fmt.Errorf("radius must be a number of meters up to %d", maxNearbyRadius)
+			return
+		}
+	}
+
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		if query.Size, err = strconv.Atoi(sizeStr); err != nil {
+			err = fmt.Errorf("size is not a valid number")
+			return
+		}
+	}
+
+	if query.Size < 1 {
+		query.Size = 1
+	} else if query.Size > maxNearbySize {
+		query.Size = maxNearbySize
+	}
+
+	return
+}
+
+// itemQueryData holds the fields of an item's data that queries filter and sort on
+type itemQueryData struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// itemSortValue returns the value of item that is compared when sorting by sortBy
+func itemSortValue(item Item, sortBy string) (value string, err error) {
+	switch sortBy {
+	case "created_at":
+		value = item.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		value = item.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "title":
+		var data itemQueryData
+
+		if err = json.Unmarshal(item.Data, &data); err != nil {
+			return
+		}
+
+		value = data.Title
+	}
+
+	return
+}
+
+// newItemCursor creates the cursor that continues a query after item
+func newItemCursor(query ItemQuery, item Item) (cursor *ItemCursor, err error) {
+	value, err := itemSortValue(item, query.Sort)
+	if err != nil {
+		return
+	}
+
+	cursor = &ItemCursor{
+		Sort:  query.Sort,
+		Order: query.Order,
+		Value: value,
+		ID:    item.ID,
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrRevisionNotFound is returned by an ItemStore when the requested revision does not exist
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Actions recorded in an item's revision history
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// ItemRevision is a snapshot of an item's data taken whenever the item changes.
+// For deletions and restorations from the trash, Data holds the data the item had at that time.
+type ItemRevision struct {
+	ItemID    int64     `json:"itemId"`
+	Revision  int       `json:"revision"`
+	Action    string    `json:"action"`
+	Data      []byte    `json:"data"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type DecodedItemRevision struct {
+	ItemID    int64                  `json:"itemId"`
+	Revision  int                    `json:"revision"`
+	Action    string                 `json:"action"`
+	Data      map[string]interface{} `json:"data"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+// Decode turns ItemRevision into DecodedItemRevision
+func (revision *ItemRevision) Decode() (decodedRevision DecodedItemRevision, err error) {
+	decodedRevision.ItemID = revision.ItemID
+	decodedRevision.Revision = revision.Revision
+	decodedRevision.Action = revision.Action
+
+	if err = json.Unmarshal(revision.Data, &decodedRevision.Data); err != nil {
+		return
+	}
+
+	decodedRevision.CreatedAt = revision.CreatedAt
+	return
+}
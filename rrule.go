@@ -0,0 +1,512 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRRulePeriods bounds how many days, weeks, months or years of a rule are looked at,
+// so that rules that never match again can't loop forever
+const maxRRulePeriods = 50000
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// RRuleWeekday is a BYDAY value such as "MO" or "-1FR".
+// N is the nth such weekday within the month or year, counted from the end when negative, or 0 for all of them.
+type RRuleWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+func (wd RRuleWeekday) String() string {
+	s := strings.ToUpper(wd.Weekday.String()[:2])
+	if wd.N != 0 {
+		s = strconv.Itoa(wd.N) + s
+	}
+	return s
+}
+
+// RRule is an RFC 5545 recurrence rule.
+// Only the parts that repeat events on whole days are supported,
+// so the time of day always comes from the start of the event.
+type RRule struct {
+	Freq       string // DAILY, WEEKLY, MONTHLY or YEARLY
+	Interval   int
+	Count      int       // 0 when the rule has no COUNT
+	Until      time.Time // zero when the rule has no UNTIL
+	UntilDate  bool      // whether UNTIL was a date rather than a time
+	ByDay      []RRuleWeekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// parseRRule parses the value of an RRULE property.
+// UNTIL times without a "Z" suffix, and UNTIL dates, are read in loc.
+func parseRRule(s string, loc *time.Location) (rule RRule, err error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		err = errors.New("must not be empty")
+		return
+	}
+
+	rule.Interval = 1
+	rule.WeekStart = time.Monday
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ";") {
+		i := strings.Index(part, "=")
+		if i < 0 {
+			err = fmt.Errorf("\"%s\" is not a NAME=VALUE pair", part)
+			return
+		}
+
+		name, value := strings.ToUpper(part[:i]), strings.ToUpper(part[i+1:])
+		if seen[name] {
+			err = fmt.Errorf("%s is given more than once", name)
+			return
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = value
+			case "SECONDLY", "MINUTELY", "HOURLY":
+				err = fmt.Errorf("FREQ=%s is not supported, events can repeat at most daily", value)
+			default:
+				err = fmt.Errorf("unknown FREQ \"%s\"", value)
+			}
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(value); err != nil || rule.Interval < 1 {
+				err = errors.New("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(value); err != nil || rule.Count < 1 {
+				err = errors.New("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			rule.Until, rule.UntilDate, err = parseRRuleUntil(value, loc)
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				var wd RRuleWeekday
+				if wd, err = parseRRuleWeekday(v); err != nil {
+					break
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRRuleNumbers(name, value, 31)
+		case "BYMONTH":
+			var months []int
+			if months, err = parseRRuleNumbers(name, value, 12); err == nil {
+				for _, month := range months {
+					if month < 0 {
+						err = errors.New("BYMONTH must be between 1 and 12")
+						break
+					}
+					rule.ByMonth = append(rule.ByMonth, time.Month(month))
+				}
+			}
+		case "BYSETPOS":
+			rule.BySetPos, err = parseRRuleNumbers(name, value, 366)
+		case "WKST":
+			var ok bool
+			if rule.WeekStart, ok = rruleWeekdays[value]; !ok {
+				err = fmt.Errorf("\"%s\" is not a day of the week", value)
+			}
+		case "BYSECOND", "BYMINUTE", "BYHOUR", "BYYEARDAY", "BYWEEKNO":
+			err = fmt.Errorf("%s is not supported", name)
+		default:
+			err = fmt.Errorf("unknown rule part \"%s\"", name)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		err = errors.New("FREQ is required")
+	case rule.Count > 0 && !rule.Until.IsZero():
+		err = errors.New("COUNT and UNTIL cannot be used together")
+	case rule.Freq == "WEEKLY" && len(rule.ByMonthDay) > 0:
+		err = errors.New("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	case len(rule.BySetPos) > 0 && len(rule.ByDay)+len(rule.ByMonthDay)+len(rule.ByMonth) == 0:
+		err = errors.New("BYSETPOS requires BYDAY, BYMONTHDAY or BYMONTH")
+	}
+
+	if err == nil && rule.Freq != "MONTHLY" && rule.Freq != "YEARLY" {
+		for _, wd := range rule.ByDay {
+			if wd.N != 0 {
+				err = fmt.Errorf("BYDAY=%s can only be used with FREQ=MONTHLY or FREQ=YEARLY", wd)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// parseRRuleUntil parses an UNTIL value, which is a date, a UTC time or a floating time
+func parseRRuleUntil(s string, loc *time.Location) (until time.Time, isDate bool, err error) {
+	if until, err = time.ParseInLocation("20060102", s, loc); err == nil {
+		isDate = true
+		return
+	}
+
+	if until, err = time.Parse("20060102T150405Z", s); err == nil {
+		return
+	}
+
+	if until, err = time.ParseInLocation("20060102T150405", s, loc); err != nil {
+		err = errors.New("UNTIL must be a date like 20060102 or a time like 20060102T150405Z")
+	}
+	return
+}
+
+// parseRRuleWeekday parses a single BYDAY value
+func parseRRuleWeekday(s string) (wd RRuleWeekday, err error) {
+	if len(s) < 2 {
+		err = fmt.Errorf("\"%s\" is not a day of the week", s)
+		return
+	}
+
+	var ok bool
+	if wd.Weekday, ok = rruleWeekdays[s[len(s)-2:]]; !ok {
+		err = fmt.Errorf("\"%s\" is not a day of the week", s)
+		return
+	}
+
+	if n := s[:len(s)-2]; n != "" {
+		if wd.N, err = strconv.Atoi(n); err != nil || wd.N == 0 || wd.N < -53 || wd.N > 53 {
+			err = fmt.Errorf("\"%s\" is not a day of the week", s)
+		}
+	}
+	return
+}
+
+// parseRRuleNumbers parses a list of non-zero numbers between -max and max
+func parseRRuleNumbers(name, s string, max int) (numbers []int, err error) {
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n == 0 || n < -max || n > max {
+			return nil, fmt.Errorf("%s must be a list of numbers between %d and %d other than 0", name, -max, max)
+		}
+		numbers = append(numbers, n)
+	}
+	return
+}
+
+// String formats the rule as the value of an RRULE property.
+// UNTIL times are given in UTC, as RFC 5545 requires for events with a time zone.
+func (rule *RRule) String() string {
+	parts := []string{"FREQ=" + rule.Freq}
+
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+
+	if rule.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+
+	if !rule.Until.IsZero() {
+		if rule.UntilDate {
+			parts = append(parts, "UNTIL="+rule.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+rule.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+
+	if len(rule.ByDay) > 0 {
+		var days []string
+		for _, wd := range rule.ByDay {
+			days = append(days, wd.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(rule.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(rule.ByMonthDay))
+	}
+
+	if len(rule.ByMonth) > 0 {
+		var months []int
+		for _, month := range rule.ByMonth {
+			months = append(months, int(month))
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+
+	if len(rule.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(rule.BySetPos))
+	}
+
+	if rule.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+strings.ToUpper(rule.WeekStart.String()[:2]))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func joinInts(numbers []int) string {
+	var s []string
+	for _, n := range numbers {
+		s = append(s, strconv.Itoa(n))
+	}
+	return strings.Join(s, ",")
+}
+
+// Between returns the starts of the occurrences of the rule that are in [from, to).
+// The rule starts at dtstart, which is always the first occurrence.
+func (rule *RRule) Between(dtstart, from, to time.Time) (starts []time.Time) {
+	rule.each(dtstart, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+
+		if !t.Before(from) {
+			starts = append(starts, t)
+		}
+		return true
+	})
+	return
+}
+
+// each calls fn with the start of every occurrence of the rule in order, until fn returns false
+func (rule *RRule) each(dtstart time.Time, fn func(t time.Time) bool) {
+	until := rule.Until
+	if rule.UntilDate {
+		until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	count := 0
+	emit := func(t time.Time) bool {
+		if (rule.Count > 0 && count >= rule.Count) || (!until.IsZero() && t.After(until)) {
+			return false
+		}
+
+		count++
+		return fn(t)
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+
+	hour, minute, second := dtstart.Clock()
+	start := civilDate(dtstart)
+
+	for i := 0; i < maxRRulePeriods; i++ {
+		for _, day := range rule.periodDays(start, i) {
+			t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, dtstart.Location())
+			if !t.After(dtstart) {
+				continue
+			}
+
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// civilDate returns the date of t at midnight UTC, which is easier to do arithmetic on
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// periodDays returns the days of the ith period of the rule that match it, in order.
+// start is the date of the first occurrence.
+func (rule *RRule) periodDays(start time.Time, i int) (days []time.Time) {
+	n := i * rule.Interval
+
+	switch rule.Freq {
+	case "DAILY":
+		day := start.AddDate(0, 0, n)
+		if rule.matchesMonth(day) && rule.matchesMonthDay(day) && rule.matchesWeekday(day) {
+			days = append(days, day)
+		}
+	case "WEEKLY":
+		weekStart := start.AddDate(0, 0, -int((start.Weekday()-rule.WeekStart+7)%7)+7*n)
+		for d := 0; d < 7; d++ {
+			day := weekStart.AddDate(0, 0, d)
+			if len(rule.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+
+			if rule.matchesMonth(day) && rule.matchesWeekday(day) {
+				days = append(days, day)
+			}
+		}
+	case "MONTHLY":
+		month := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		if rule.matchesMonth(month) {
+			days = rule.monthDays(month, start.Day())
+		}
+	case "YEARLY":
+		year := start.Year() + n
+
+		switch {
+		case len(rule.ByDay) > 0 && len(rule.ByMonth) == 0 && len(rule.ByMonthDay) == 0:
+			// Ordinals count the weekdays of the whole year
+			days = byDayInRange(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC), rule.ByDay)
+		case len(rule.ByDay)+len(rule.ByMonth)+len(rule.ByMonthDay) > 0:
+			months := rule.ByMonth
+			if len(months) == 0 {
+				months = []time.Month{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+			}
+
+			for _, month := range months {
+				days = append(days, rule.monthDays(time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), start.Day())...)
+			}
+			days = sortDays(days)
+		default:
+			if day := time.Date(year, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC); day.Day() == start.Day() {
+				days = append(days, day)
+			}
+		}
+	}
+
+	return rule.applySetPos(days)
+}
+
+// monthDays returns the days of the month beginning at first that match BYMONTHDAY and BYDAY,
+// or the day with the number defaultDay when the rule has neither
+func (rule *RRule) monthDays(first time.Time, defaultDay int) (days []time.Time) {
+	last := first.AddDate(0, 1, -1)
+
+	if len(rule.ByDay) > 0 {
+		for _, day := range byDayInRange(first, last, rule.ByDay) {
+			if rule.matchesMonthDay(day) {
+				days = append(days, day)
+			}
+		}
+		return
+	}
+
+	if len(rule.ByMonthDay) > 0 {
+		for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+			if rule.matchesMonthDay(d) {
+				days = append(days, d)
+			}
+		}
+		return
+	}
+
+	if defaultDay <= last.Day() {
+		days = append(days, first.AddDate(0, 0, defaultDay-1))
+	}
+	return
+}
+
+// byDayInRange returns the days between first and last, inclusive, that match any of byDay in order.
+// Ordinals count the matching weekdays within the range.
+func byDayInRange(first, last time.Time, byDay []RRuleWeekday) (days []time.Time) {
+	for _, wd := range byDay {
+		var matches []time.Time
+		for d := first.AddDate(0, 0, int(wd.Weekday-first.Weekday()+7)%7); !d.After(last); d = d.AddDate(0, 0, 7) {
+			matches = append(matches, d)
+		}
+
+		switch {
+		case wd.N == 0:
+			days = append(days, matches...)
+		case wd.N > 0 && wd.N <= len(matches):
+			days = append(days, matches[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(matches):
+			days = append(days, matches[len(matches)+wd.N])
+		}
+	}
+
+	days = sortDays(days)
+	return
+}
+
+// applySetPos picks the days of a period given by BYSETPOS
+func (rule *RRule) applySetPos(days []time.Time) []time.Time {
+	if len(rule.BySetPos) == 0 {
+		return days
+	}
+
+	var picked []time.Time
+	for _, pos := range rule.BySetPos {
+		if pos > 0 && pos <= len(days) {
+			picked = append(picked, days[pos-1])
+		} else if pos < 0 && -pos <= len(days) {
+			picked = append(picked, days[len(days)+pos])
+		}
+	}
+
+	return sortDays(picked)
+}
+
+// sortDays sorts days and removes duplicates in place
+func sortDays(days []time.Time) []time.Time {
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	unique := days[:0]
+	for i, day := range days {
+		if i == 0 || !day.Equal(days[i-1]) {
+			unique = append(unique, day)
+		}
+	}
+	return unique
+}
+
+func (rule *RRule) matchesMonth(day time.Time) bool {
+	if len(rule.ByMonth) == 0 {
+		return true
+	}
+
+	for _, month := range rule.ByMonth {
+		if day.Month() == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *RRule) matchesMonthDay(day time.Time) bool {
+	if len(rule.ByMonthDay) == 0 {
+		return true
+	}
+
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, monthDay := range rule.ByMonthDay {
+		if monthDay == day.Day() || (monthDay < 0 && daysInMonth+1+monthDay == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday checks BYDAY for rules that repeat daily or weekly, where it has no ordinals
+func (rule *RRule) matchesWeekday(day time.Time) bool {
+	if len(rule.ByDay) == 0 {
+		return true
+	}
+
+	for _, wd := range rule.ByDay {
+		if day.Weekday() == wd.Weekday {
+			return true
+		}
+	}
+	return false
+}
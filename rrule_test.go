@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRRuleDates expands rule from dtstart, given in loc, and formats the starts in loc
+func testRRuleDates(t *testing.T, rule, dtstart, tz string, limit int) []string {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := time.ParseInLocation(eventTimeLayout, dtstart, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := parseRRule(rule, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dates []string
+	r.each(start, func(t time.Time) bool {
+		dates = append(dates, t.In(loc).Format(eventTimeLayout))
+		return len(dates) < limit
+	})
+	return dates
+}
+
+func TestRRuleExpansion(t *testing.T) {
+	// Examples from RFC 5545, section 3.8.5.3
+	assert.Equal(t, []string{
+		"1997-09-02T09:00", "1997-09-03T09:00", "1997-09-04T09:00", "1997-09-05T09:00", "1997-09-06T09:00",
+		"1997-09-07T09:00", "1997-09-08T09:00", "1997-09-09T09:00", "1997-09-10T09:00", "1997-09-11T09:00",
+	}, testRRuleDates(t, "FREQ=DAILY;COUNT=10", "1997-09-02T09:00", "America/New_York", 100))
+
+	assert.Equal(t, []string{
+		"1997-09-02T09:00", "1997-09-04T09:00", "1997-09-09T09:00", "1997-09-11T09:00", "1997-09-16T09:00",
+		"1997-09-18T09:00", "1997-09-23T09:00", "1997-09-25T09:00", "1997-09-30T09:00", "1997-10-02T09:00",
+	}, testRRuleDates(t, "RRULE:FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH", "1997-09-02T09:00", "America/New_York", 100))
+
+	assert.Equal(t, []string{
+		"1997-09-01T09:00", "1997-09-03T09:00", "1997-09-05T09:00", "1997-09-15T09:00", "1997-09-17T09:00",
+		"1997-09-19T09:00", "1997-09-29T09:00", "1997-10-01T09:00",
+	}, testRRuleDates(t, "FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO,WE,FR", "1997-09-01T09:00", "America/New_York", 8))
+
+	assert.Equal(t, []string{
+		"1997-09-05T09:00", "1997-10-03T09:00", "1997-11-07T09:00", "1997-12-05T09:00", "1998-01-02T09:00",
+	}, testRRuleDates(t, "FREQ=MONTHLY;COUNT=5;BYDAY=1FR", "1997-09-05T09:00", "America/New_York", 100))
+
+	assert.Equal(t, []string{
+		"1997-09-30T09:00", "1997-10-31T09:00", "1997-11-28T09:00", "1997-12-31T09:00",
+	}, testRRuleDates(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "1997-09-30T09:00", "America/New_York", 4))
+
+	// Months without the day are skipped rather than moved
+	assert.Equal(t, []string{
+		"2026-01-31T18:00", "2026-03-31T18:00", "2026-05-31T18:00", "2026-07-31T18:00",
+	}, testRRuleDates(t, "FREQ=MONTHLY;COUNT=4", "2026-01-31T18:00", "Asia/Singapore", 100))
+
+	assert.Equal(t, []string{
+		"2026-03-29T10:00", "2027-03-28T10:00", "2028-03-26T10:00",
+	}, testRRuleDates(t, "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;COUNT=3", "2026-03-29T10:00", "Europe/Berlin", 100))
+
+	// The time of day stays the same when the clocks change
+	assert.Equal(t, []string{
+		"2026-03-21T19:00", "2026-03-28T19:00", "2026-04-04T19:00",
+	}, testRRuleDates(t, "FREQ=WEEKLY;UNTIL=20260404", "2026-03-21T19:00", "Europe/Berlin", 100))
+
+	// Rules that never match again stop instead of looping forever
+	assert.Equal(t, []string{"2026-01-30T10:00"}, testRRuleDates(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2026-01-30T10:00", "UTC", 100))
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for rule, message := range map[string]string{
+		"":                                  "must not be empty",
+		"COUNT=3":                           "FREQ is required",
+		"FREQ=HOURLY":                       "FREQ=HOURLY is not supported, events can repeat at most daily",
+		"FREQ=WEEKLY;BYDAY=1MO":             "BYDAY=1MO can only be used with FREQ=MONTHLY or FREQ=YEARLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101": "COUNT and UNTIL cannot be used together",
+		"FREQ=MONTHLY;BYMONTHDAY=32":        "BYMONTHDAY must be a list of numbers between -31 and 31 other than 0",
+		"FREQ=DAILY;BYHOUR=9":               "BYHOUR is not supported",
+		"FREQ=DAILY;FREQ=WEEKLY":            "FREQ is given more than once",
+	} {
+		_, err := parseRRule(rule, time.UTC)
+		if assert.Error(t, err, rule) {
+			assert.Equal(t, message, err.Error(), rule)
+		}
+	}
+
+	rule, err := parseRRule("freq=monthly;until=20261231T235959;byday=-1fr", time.FixedZone("SGT", 8*60*60))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "FREQ=MONTHLY;UNTIL=20261231T155959Z;BYDAY=-1FR", rule.String())
+	}
+}
+
+func TestEventOccurrences(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Singapore")
+	date := func(s string) time.Time {
+		t, _ := time.ParseInLocation(eventTimeLayout, s, loc)
+		return t
+	}
+
+	event := Event{
+		StartsAt: "2026-05-02T08:00",
+		EndsAt:   "2026-05-02T13:00",
+		TimeZone: "Asia/Singapore",
+		RRule:    "FREQ=WEEKLY;BYDAY=SA",
+		ExDates:  []string{"2026-05-09T08:00"},
+		RDates:   []string{"2026-05-14T17:00"},
+	}
+
+	// The occurrence that is still going on at the start of the window is included
+	occurrences, err := event.Occurrences(date("2026-05-02T12:00"), date("2026-05-17T00:00"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var starts []string
+	for _, occurrence := range occurrences {
+		starts = append(starts, occurrence.Start.Format(eventTimeLayout))
+		assert.Equal(t, 5*time.Hour, occurrence.End.Sub(occurrence.Start))
+	}
+	assert.Equal(t, []string{"2026-05-02T08:00", "2026-05-14T17:00", "2026-05-16T08:00"}, starts)
+
+	// Events that don't repeat have a single occurrence
+	event = Event{StartsAt: "2026-05-01", TimeZone: "Asia/Singapore", AllDay: true}
+	occurrences, err = event.Occurrences(date("2026-05-01T23:00"), date("2026-05-03T00:00"))
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 1)
+
+	occurrences, err = event.Occurrences(date("2026-05-02T00:00"), date("2026-05-03T00:00"))
+	assert.NoError(t, err)
+	assert.Empty(t, occurrences)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	eventDateLayout = "2006-01-02"       // the format of the days of all-day events
+	eventTimeLayout = "2006-01-02T15:04" // the format of wall-clock times in the event's time zone
+)
+
+// EventSession is one of several sittings of an event, such as the days of a festival
+type EventSession struct {
+	Title    string `toml:"title" json:"title,omitempty"`
+	StartsAt string `toml:"starts_at" json:"startsAt"`
+	EndsAt   string `toml:"ends_at" json:"endsAt,omitempty"`
+}
+
+// EventPeriod is a span of time that an event takes place in.
+// End is exclusive, so an all-day event ends at midnight after its last day.
+type EventPeriod struct {
+	Title string
+	Start time.Time
+	End   time.Time
+}
+
+// scheduleError is returned by parseEventPeriod for a field that is not valid
+type scheduleError struct {
+	Field   string
+	Message string
+}
+
+func (err *scheduleError) Error() string {
+	return err.Field + " " + err.Message
+}
+
+// parseEventTime parses a date of an all-day event, or a wall-clock time of any other event, in loc
+func parseEventTime(s string, allDay bool, loc *time.Location) (t time.Time, err error) {
+	if allDay {
+		if t, err = time.ParseInLocation(eventDateLayout, s, loc); err != nil {
+			err = fmt.Errorf("must be a date like %s", eventDateLayout)
+		}
+		return
+	}
+
+	if t, err = time.ParseInLocation(eventTimeLayout, s, loc); err != nil {
+		if t, err = time.ParseInLocation(eventTimeLayout+":05", s, loc); err != nil {
+			err = fmt.Errorf("must be a local time like %s", eventTimeLayout)
+		}
+	}
+	return
+}
+
+// parseEventPeriod parses the start and the optional end of an event or a session.
+// The end of an all-day event is its last day, and a missing end means the event lasts a day,
+// or no time at all when it isn't all-day.
+func parseEventPeriod(startsAt, endsAt string, allDay bool, loc *time.Location) (period EventPeriod, err error) {
+	if period.Start, err = parseEventTime(startsAt, allDay, loc); err != nil {
+		err = &scheduleError{"startsAt", err.Error()}
+		return
+	}
+
+	period.End = period.Start
+	if endsAt != "" {
+		if period.End, err = parseEventTime(endsAt, allDay, loc); err != nil {
+			err = &scheduleError{"endsAt", err.Error()}
+			return
+		}
+	}
+
+	if allDay {
+		if period.End.Before(period.Start) {
+			err = &scheduleError{"endsAt", "must not be before startsAt"}
+			return
+		}
+
+		period.End = period.End.AddDate(0, 0, 1)
+	} else if endsAt != "" && !period.End.After(period.Start) {
+		err = &scheduleError{"endsAt", "must be after startsAt"}
+	}
+
+	return
+}
+
+// Location returns the time zone of the event
+func (event *Event) Location() (*time.Location, error) {
+	if event.TimeZone == "" || event.TimeZone == "Local" {
+		return nil, fmt.Errorf("time zone is required")
+	}
+
+	return time.LoadLocation(event.TimeZone)
+}
+
+// Periods returns the times that the event takes place at in order.
+// Those are its sessions if it has any, or else its own start and end.
+// Events without a schedule have no periods.
+func (event *Event) Periods() (periods []EventPeriod, err error) {
+	if event.StartsAt == "" && len(event.Sessions) == 0 {
+		return
+	}
+
+	loc, err := event.Location()
+	if err != nil {
+		return
+	}
+
+	if len(event.Sessions) == 0 {
+		var period EventPeriod
+		if period, err = parseEventPeriod(event.StartsAt, event.EndsAt, event.AllDay, loc); err != nil {
+			return
+		}
+
+		periods = append(periods, period)
+		return
+	}
+
+	for i, session := range event.Sessions {
+		var period EventPeriod
+		if period, err = parseEventPeriod(session.StartsAt, session.EndsAt, event.AllDay, loc); err != nil {
+			err = fmt.Errorf("session %d: %s", i, err)
+			return
+		}
+
+		period.Title = session.Title
+		periods = append(periods, period)
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool { return periods[i].Start.Before(periods[j].Start) })
+	return
+}
+
+// ZolaEventSession is the format of EventSession used by Zola
+type ZolaEventSession struct {
+	Title     string `toml:"title"`
+	StartsAt  string `toml:"starts_at"`
+	EndsAt    string `toml:"ends_at"`
+	StartDate string `toml:"start_date"`
+	EndDate   string `toml:"end_date"`
+}
+
+// zolaEventSession converts an EventPeriod into the format used by Zola.
+// EndDate is the last day of the period, which is what templates show for all-day events.
+func zolaEventSession(period EventPeriod, allDay bool) ZolaEventSession {
+	lastDay := period.End
+	if allDay {
+		lastDay = lastDay.AddDate(0, 0, -1)
+	}
+
+	return ZolaEventSession{
+		Title:     period.Title,
+		StartsAt:  period.Start.Format(time.RFC3339),
+		EndsAt:    period.End.Format(time.RFC3339),
+		StartDate: period.Start.Format(eventDateLayout),
+		EndDate:   lastDay.Format(eventDateLayout),
+	}
+}
+
+// Overlaps reports whether the period takes place at any time in [from, to).
+// Periods without a duration overlap when they start within it.
+func (period EventPeriod) Overlaps(from, to time.Time) bool {
+	if period.End.Equal(period.Start) {
+		return !period.Start.Before(from) && period.Start.Before(to)
+	}
+
+	return period.Start.Before(to) && period.End.After(from)
+}
+
+// Recurs reports whether the event repeats
+func (event *Event) Recurs() bool {
+	return event.RRule != "" || len(event.RDates) > 0
+}
+
+// Occurrences returns the periods of the event that overlap [from, to) in order.
+// Recurring events repeat their start and end by their recurrence rule and extra starts,
+// leaving out the excluded starts.
+func (event *Event) Occurrences(from, to time.Time) (occurrences []EventPeriod, err error) {
+	if !event.Recurs() {
+		var periods []EventPeriod
+		if periods, err = event.Periods(); err != nil {
+			return
+		}
+
+		for _, period := range periods {
+			if period.Overlaps(from, to) {
+				occurrences = append(occurrences, period)
+			}
+		}
+		return
+	}
+
+	loc, err := event.Location()
+	if err != nil {
+		return
+	}
+
+	first, err := parseEventPeriod(event.StartsAt, event.EndsAt, event.AllDay, loc)
+	if err != nil {
+		return
+	}
+
+	// Occurrences that started before from may still be going on.
+	// All-day occurrences last whole days, which can be an hour longer when the clocks change.
+	duration := first.End.Sub(first.Start)
+	days := int(civilDate(first.End).Sub(civilDate(first.Start)).Hours() / 24)
+	since := from.Add(-duration - time.Hour)
+
+	var starts []time.Time
+	if event.RRule != "" {
+		var rule RRule
+		if rule, err = parseRRule(event.RRule, loc); err != nil {
+			err = fmt.Errorf("rrule %s", err)
+			return
+		}
+
+		starts = rule.Between(first.Start, since, to)
+	} else if !first.Start.Before(since) && first.Start.Before(to) {
+		starts = append(starts, first.Start)
+	}
+
+	for _, rdate := range event.RDates {
+		var start time.Time
+		if start, err = parseEventTime(rdate, event.AllDay, loc); err != nil {
+			err = fmt.Errorf("rDates %s", err)
+			return
+		}
+
+		if !start.Before(since) && start.Before(to) {
+			starts = append(starts, start)
+		}
+	}
+
+	excluded := make(map[int64]bool)
+	for _, exdate := range event.ExDates {
+		var start time.Time
+		if start, err = parseEventTime(exdate, event.AllDay, loc); err != nil {
+			err = fmt.Errorf("exDates %s", err)
+			return
+		}
+
+		excluded[start.Unix()] = true
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	for i, start := range starts {
+		if excluded[start.Unix()] || (i > 0 && start.Equal(starts[i-1])) {
+			continue
+		}
+
+		period := EventPeriod{Start: start, End: start.Add(duration)}
+		if event.AllDay {
+			period.End = start.AddDate(0, 0, days)
+		}
+
+		if period.Overlaps(from, to) {
+			occurrences = append(occurrences, period)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventPeriods(t *testing.T) {
+	event := Event{StartsAt: "2026-05-01T19:30", EndsAt: "2026-05-01T22:00", TimeZone: "Asia/Singapore"}
+
+	periods, err := event.Periods()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, periods, 1)
+	assert.Equal(t, "2026-05-01T11:30:00Z", periods[0].Start.UTC().Format(time.RFC3339))
+	assert.Equal(t, "2026-05-01T14:00:00Z", periods[0].End.UTC().Format(time.RFC3339))
+
+	// The end of an all-day session is its last day, so the period ends at the following midnight
+	event = Event{TimeZone: "Europe/Berlin", AllDay: true, Sessions: []EventSession{
+		{Title: "Day 2", StartsAt: "2026-03-29"},
+		{Title: "Day 1", StartsAt: "2026-03-27", EndsAt: "2026-03-28"},
+	}}
+
+	periods, err = event.Periods()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "Day 1", periods[0].Title)
+	assert.Equal(t, "2026-03-26T23:00:00Z", periods[0].Start.UTC().Format(time.RFC3339))
+	assert.Equal(t, "2026-03-28T23:00:00Z", periods[0].End.UTC().Format(time.RFC3339))
+	assert.Equal(t, "2026-03-29T22:00:00Z", periods[1].End.UTC().Format(time.RFC3339), "the clocks change on the last day")
+
+	periods, err = (&Event{}).Periods()
+	assert.NoError(t, err)
+	assert.Empty(t, periods)
+}
+
+func TestEventZolaSchedule(t *testing.T) {
+	blobStore = NewFSBlobStore(t.TempDir())
+	cover := testJPEG(t, 8, 8)
+	blobStore.Put(BlobInfo{Key: "cover", Size: int64(len(cover)), ContentType: "image/jpeg"}, bytes.NewReader(cover))
+
+	event := Event{ID: 7, Title: "Fair", CoverImageURL: "cover", TimeZone: "Asia/Singapore", AllDay: true, Sessions: []EventSession{
+		{StartsAt: "2026-05-01"},
+		{StartsAt: "2026-05-02", EndsAt: "2026-05-03"},
+	}}
+
+	zolaEvent, err := event.Zola()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "2026-05-01", zolaEvent.Extra.StartDate)
+	assert.Equal(t, "2026-05-03", zolaEvent.Extra.EndDate)
+	assert.Len(t, zolaEvent.Extra.Sessions, 2)
+
+	var buf bytes.Buffer
+	var front map[string]interface{}
+	if assert.NoError(t, toml.NewEncoder(&buf).Encode(zolaEvent)) && assert.NoError(t, toml.Unmarshal(buf.Bytes(), &front)) {
+		extra := front["extra"].(map[string]interface{})
+		assert.Equal(t, "2026-05-01T00:00:00+08:00", extra["starts_at"])
+		assert.Equal(t, "2026-05-04T00:00:00+08:00", extra["ends_at"])
+		assert.Equal(t, true, extra["all_day"])
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrItemNotFound is returned by an ItemStore when the requested item does not exist
+var ErrItemNotFound = errors.New("item not found")
+
+// ErrVersionMismatch is returned by an ItemStore when an item has changed since the expected version
+var ErrVersionMismatch = errors.New("item version does not match")
+
+// ItemStore is the storage backend used by the API handlers to persist items.
+// Item data is passed around as raw JSON so that stores don't have to know
+// anything about the item types.
+//
+// Every change made through Create, Update, Delete, Restore and RestoreRevision
+// is recorded in the item's revision history.
+type ItemStore interface {
+	// Get fetches a single item by its ID
+	Get(id int64) (Item, error)
+
+	// List fetches a page of items matching query
+	List(query ItemQuery) (ItemPage, error)
+
+	// ListByType fetches all items whose data has the given "type"
+	ListByType(typ string) ([]Item, error)
+
+	// Nearby fetches the items whose coordinates are within the radius of query,
+	// closest first, along with their distance
+	Nearby(query NearbyQuery) ([]NearbyItem, error)
+
+	// Create inserts a new item and returns it
+	Create(data []byte) (Item, error)
+
+	// Update replaces the data of an existing item and returns it.
+	// Unless version is 0, the update only happens when the item is still
+	// at that version, otherwise ErrVersionMismatch is returned.
+	Update(id int64, data []byte, version int64) (Item, error)
+
+	// Delete moves an item to the trash. Items in the trash are left out
+	// of every other method except the revision ones.
+	// The version is checked the same way as for Update.
+	Delete(id int64, version int64) error
+
+	// ListTrash fetches the items in the trash, most recently deleted first
+	ListTrash() ([]Item, error)
+
+	// Restore takes an item out of the trash
+	Restore(id int64) (Item, error)
+
+	// Purge permanently removes the items, and their revisions, that were
+	// moved to the trash before the given time. It returns the removed items.
+	Purge(before time.Time) ([]Item, error)
+
+	// ListRevisions fetches the revision history of an item, oldest first
+	ListRevisions(id int64) ([]ItemRevision, error)
+
+	// GetRevision fetches a single revision of an item
+	GetRevision(id int64, revision int) (ItemRevision, error)
+
+	// RestoreRevision brings an item back to the data it had at a revision.
+	// This takes the item out of the trash as well, and is recorded as a new revision.
+	// The version is checked the same way as for Update, whether or not the item is in the trash.
+	RestoreRevision(id int64, revision int, version int64) (Item, error)
+}
+
+// openStores sets up the stores used by the API handlers.
+// kind is either "postgres" or "memory".
+func openStores(kind string) error {
+	switch kind {
+	case "postgres":
+		db, err := openPostgres(dbConnStr)
+		if err != nil {
+			return err
+		}
+
+		itemStore = NewPostgresItemStore(db)
+		userStore = NewPostgresUserStore(db)
+		apiKeyStore = NewPostgresAPIKeyStore(db)
+	case "memory":
+		itemStore = NewMemoryItemStore()
+		userStore = NewMemoryUserStore()
+		apiKeyStore = NewMemoryAPIKeyStore()
+	default:
+		return fmt.Errorf("unknown store \"%s\"", kind)
+	}
+
+	return nil
+}
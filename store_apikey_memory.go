@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// MemoryAPIKeyStore is an APIKeyStore that keeps everything in memory
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys []APIKey
+}
+
+// NewMemoryAPIKeyStore creates an empty MemoryAPIKeyStore
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{}
+}
+
+// CreateAPIKey stores a new key
+func (store *MemoryAPIKeyStore) CreateAPIKey(key APIKey) (APIKey, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key.ID = int64(len(store.keys) + 1)
+	key.CreatedAt = time.Now()
+	store.keys = append(store.keys, key)
+
+	return key, nil
+}
+
+// GetAPIKey fetches an active key by the hash of its token
+func (store *MemoryAPIKeyStore) GetAPIKey(keyHash []byte) (APIKey, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, key := range store.keys {
+		if bytes.Equal(key.KeyHash, keyHash) && key.Active(time.Now()) {
+			return key, nil
+		}
+	}
+
+	return APIKey{}, ErrAPIKeyNotFound
+}
+
+// ListAPIKeys fetches all keys, including revoked and expired ones
+func (store *MemoryAPIKeyStore) ListAPIKeys() ([]APIKey, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return append([]APIKey{}, store.keys...), nil
+}
+
+// RevokeAPIKey revokes a key by its prefix
+func (store *MemoryAPIKeyStore) RevokeAPIKey(prefix string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for i, key := range store.keys {
+		if key.Prefix == prefix && key.RevokedAt == nil {
+			now := time.Now()
+			store.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+
+	return ErrAPIKeyNotFound
+}
+
+// TouchAPIKey records that a key has just been used
+func (store *MemoryAPIKeyStore) TouchAPIKey(id int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for i, key := range store.keys {
+		if key.ID == id {
+			now := time.Now()
+			store.keys[i].LastUsedAt = &now
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = "id, name, prefix, key_hash, item_types, actions, expires_at, last_used_at, revoked_at, created_at"
+
+// PostgresAPIKeyStore is an APIKeyStore backed by PostgreSQL
+type PostgresAPIKeyStore struct {
+	db *sql.DB
+}
+
+// NewPostgresAPIKeyStore creates a PostgresAPIKeyStore that uses the connection pool db
+func NewPostgresAPIKeyStore(db *sql.DB) *PostgresAPIKeyStore {
+	return &PostgresAPIKeyStore{db: db}
+}
+
+// scanAPIKey reads the columns listed in apiKeyColumns into an APIKey
+func scanAPIKey(row rowScanner) (key APIKey, err error) {
+	err = row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		(*pq.StringArray)(&key.ItemTypes),
+		(*pq.StringArray)(&key.Actions),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		err = ErrAPIKeyNotFound
+	}
+	return
+}
+
+// CreateAPIKey stores a new key
+func (store *PostgresAPIKeyStore) CreateAPIKey(key APIKey) (APIKey, error) {
+	if key.ItemTypes == nil {
+		key.ItemTypes = []string{}
+	}
+
+	return scanAPIKey(store.db.QueryRow(
+		`INSERT INTO api_keys (name, prefix, key_hash, item_types, actions, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+apiKeyColumns,
+		key.Name, key.Prefix, key.KeyHash, pq.StringArray(key.ItemTypes), pq.StringArray(key.Actions), key.ExpiresAt,
+	))
+}
+
+// GetAPIKey fetches an active key by the hash of its token
+func (store *PostgresAPIKeyStore) GetAPIKey(keyHash []byte) (APIKey, error) {
+	return scanAPIKey(store.db.QueryRow(
+		`SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`,
+		keyHash,
+	))
+}
+
+// ListAPIKeys fetches all keys, including revoked and expired ones
+func (store *PostgresAPIKeyStore) ListAPIKeys() (keys []APIKey, err error) {
+	rows, err := store.db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	keys = make([]APIKey, 0)
+
+	for rows.Next() {
+		var key APIKey
+
+		if key, err = scanAPIKey(rows); err != nil {
+			return
+		}
+
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// RevokeAPIKey revokes a key by its prefix
+func (store *PostgresAPIKeyStore) RevokeAPIKey(prefix string) error {
+	result, err := store.db.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE prefix = $1 AND revoked_at IS NULL", prefix)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey records that a key has just been used.
+// To spare the database a write on every request, this is recorded at most once a minute.
+func (store *PostgresAPIKeyStore) TouchAPIKey(id int64) error {
+	_, err := store.db.Exec(`UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	return err
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FSBlobStore is a BlobStore that keeps blobs as files under a root directory.
+// The content type and metadata of each blob are kept in a JSON file under "<root>/.meta".
+type FSBlobStore struct {
+	root string
+}
+
+// NewFSBlobStore creates an FSBlobStore at root, which is created when the first blob is stored
+func NewFSBlobStore(root string) *FSBlobStore {
+	return &FSBlobStore{root: root}
+}
+
+// fsBlobMeta is the content of the metadata file of a blob
+type fsBlobMeta struct {
+	ContentType string            `json:"contentType"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+func (store *FSBlobStore) path(key string) string {
+	return filepath.Join(store.root, filepath.FromSlash(key))
+}
+
+func (store *FSBlobStore) metaPath(key string) string {
+	return filepath.Join(store.root, ".meta", filepath.FromSlash(key)+".json")
+}
+
+// writeFile writes a file atomically by renaming a temporary file into place
+func (store *FSBlobStore) writeFile(filename string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Put stores the content of r under info.Key
+func (store *FSBlobStore) Put(info BlobInfo, r io.Reader) error {
+	if err := checkBlobKey(info.Key); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(fsBlobMeta{ContentType: info.ContentType, Metadata: info.Metadata})
+	if err != nil {
+		return err
+	}
+
+	// Write the metadata first, so that a blob is never visible without it
+	if err = store.writeFile(store.metaPath(info.Key), bytes.NewReader(meta)); err != nil {
+		return err
+	}
+
+	return store.writeFile(store.path(info.Key), r)
+}
+
+// Get opens a blob for reading
+func (store *FSBlobStore) Get(key string) (io.ReadCloser, BlobInfo, error) {
+	info, err := store.Stat(key)
+	if err != nil {
+		return nil, info, err
+	}
+
+	f, err := os.Open(store.path(key))
+	if os.IsNotExist(err) {
+		err = ErrBlobNotFound
+	}
+
+	return f, info, err
+}
+
+// Stat fetches the description of a blob
+func (store *FSBlobStore) Stat(key string) (info BlobInfo, err error) {
+	if err = checkBlobKey(key); err != nil {
+		return
+	}
+
+	fi, err := os.Stat(store.path(key))
+	if os.IsNotExist(err) || (err == nil && fi.IsDir()) {
+		err = ErrBlobNotFound
+		return
+	} else if err != nil {
+		return
+	}
+
+	info = BlobInfo{
+		Key:     key,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+	}
+
+	// Blobs written before metadata was kept have none
+	data, err := ioutil.ReadFile(store.metaPath(key))
+	if os.IsNotExist(err) {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	var meta fsBlobMeta
+	if err = json.Unmarshal(data, &meta); err != nil {
+		return
+	}
+
+	info.ContentType = meta.ContentType
+	info.Metadata = meta.Metadata
+	return
+}
+
+// Delete removes a blob along with its metadata
+func (store *FSBlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+
+	if err := os.Remove(store.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Remove(store.metaPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// List describes all blobs whose key starts with prefix
+func (store *FSBlobStore) List(prefix string) (infos []BlobInfo, err error) {
+	infos = make([]BlobInfo, 0)
+
+	err = filepath.Walk(store.root, func(filename string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if strings.HasPrefix(fi.Name(), ".") && filename != store.root {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if fi.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(store.root, filename)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := store.Stat(key)
+		if err != nil {
+			return err
+		}
+
+		infos = append(infos, info)
+		return nil
+	})
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the hex SHA-256 hash of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config holds the connection settings of an S3BlobStore
+type S3Config struct {
+	Endpoint        string // such as https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3BlobStore is a BlobStore backed by a bucket of Amazon S3 or a compatible service such as MinIO.
+// Requests use path-style URLs and are signed with AWS Signature Version 4.
+type S3BlobStore struct {
+	config S3Config
+	client *http.Client
+}
+
+// NewS3BlobStore creates an S3BlobStore for the bucket described by config
+func NewS3BlobStore(config S3Config) *S3BlobStore {
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	return &S3BlobStore{config: config, client: http.DefaultClient}
+}
+
+// s3URIEncode encodes s the way Signature Version 4 expects, optionally keeping slashes
+func s3URIEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && keepSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signS3Request adds the headers of AWS Signature Version 4 to req.
+// All headers already set on req are signed along with the host.
+func signS3Request(req *http.Request, config S3Config, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, s3URIEncode(key, false)+"="+s3URIEncode(value, false))
+		}
+	}
+
+	canonicalURI := req.URL.Path
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3URIEncode(canonicalURI, true),
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// do sends a signed request for key, or for the bucket itself when key is empty
+func (store *S3BlobStore) do(method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := store.config.Endpoint + "/" + store.config.Bucket
+	if key != "" {
+		u += "/" + s3URIEncode(key, true)
+	}
+	if len(query) > 0 {
+		u += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+
+	signS3Request(req, store.config, payloadHash, time.Now())
+
+	resp, err := store.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == 404 {
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+
+		var s3Error struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		xml.Unmarshal(data, &s3Error)
+
+		return nil, fmt.Errorf("s3: %s %s: %s %s %s", method, key, resp.Status, s3Error.Code, s3Error.Message)
+	}
+
+	return resp, nil
+}
+
+// blobInfoFromHeader reads the description of a blob from the response to a GET or HEAD request
+func blobInfoFromHeader(key string, header http.Header) (info BlobInfo) {
+	info.Key = key
+	info.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	info.ContentType = header.Get("Content-Type")
+	info.ModTime, _ = http.ParseTime(header.Get("Last-Modified"))
+
+	for name, values := range header {
+		if strings.HasPrefix(name, "X-Amz-Meta-") && len(values) > 0 {
+			if info.Metadata == nil {
+				info.Metadata = make(map[string]string)
+			}
+			info.Metadata[strings.ToLower(strings.TrimPrefix(name, "X-Amz-Meta-"))] = values[0]
+		}
+	}
+
+	return
+}
+
+// Put stores the content of r under info.Key.
+// Metadata keys are stored in lower case, since S3 doesn't keep their case.
+func (store *S3BlobStore) Put(info BlobInfo, r io.Reader) error {
+	if err := checkBlobKey(info.Key); err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	if info.ContentType != "" {
+		header.Set("Content-Type", info.ContentType)
+	}
+	for k, v := range info.Metadata {
+		header.Set("X-Amz-Meta-"+strings.ToLower(k), v)
+	}
+
+	resp, err := store.do("PUT", info.Key, nil, header, ioutil.NopCloser(r), info.Size)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// Get opens a blob for reading
+func (store *S3BlobStore) Get(key string) (io.ReadCloser, BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	resp, err := store.do("GET", key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	return resp.Body, blobInfoFromHeader(key, resp.Header), nil
+}
+
+// Stat fetches the description of a blob
+func (store *S3BlobStore) Stat(key string) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+
+	resp, err := store.do("HEAD", key, nil, nil, nil, 0)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+
+	return blobInfoFromHeader(key, resp.Header), nil
+}
+
+// Delete removes a blob
+func (store *S3BlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+
+	resp, err := store.do("DELETE", key, nil, nil, nil, 0)
+	if err == ErrBlobNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// List describes all blobs whose key starts with prefix.
+// The content type and metadata are not included, since S3 doesn't list them.
+func (store *S3BlobStore) List(prefix string) (infos []BlobInfo, err error) {
+	infos = make([]BlobInfo, 0)
+	continuationToken := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		var resp *http.Response
+		if resp, err = store.do("GET", "", query, nil, nil, 0); err != nil {
+			return
+		}
+
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return
+		}
+
+		for _, object := range result.Contents {
+			infos = append(infos, BlobInfo{
+				Key:     object.Key,
+				Size:    object.Size,
+				ModTime: object.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return
+		}
+
+		continuationToken = result.NextContinuationToken
+	}
+}
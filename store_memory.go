@@ -0,0 +1,454 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryItemStore is an ItemStore that keeps everything in memory.
+// It is useful for running the API without a database and for tests.
+type MemoryItemStore struct {
+	mu        sync.RWMutex
+	items     map[int64]Item
+	revisions map[int64][]ItemRevision
+	nextID    int64
+}
+
+// NewMemoryItemStore creates an empty MemoryItemStore
+func NewMemoryItemStore() *MemoryItemStore {
+	return &MemoryItemStore{
+		items:     make(map[int64]Item),
+		revisions: make(map[int64][]ItemRevision),
+		nextID:    1,
+	}
+}
+
+// sorted returns the items that are not in the trash ordered by ID. The caller must hold the lock.
+func (store *MemoryItemStore) sorted() []Item {
+	items := make([]Item, 0, len(store.items))
+	for _, item := range store.items {
+		if item.DeletedAt == nil {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+
+	return items
+}
+
+// Get fetches a single item by its ID
+func (store *MemoryItemStore) Get(id int64) (Item, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	item, ok := store.items[id]
+	if !ok || item.DeletedAt != nil {
+		return Item{}, ErrItemNotFound
+	}
+
+	return item, nil
+}
+
+// matchesItemQuery reports whether data passes the filters of query
+func matchesItemQuery(data itemQueryData, query ItemQuery) bool {
+	if query.Type != "" && data.Type != query.Type {
+		return false
+	}
+
+	if len(query.Types) > 0 {
+		found := false
+		for _, typ := range query.Types {
+			if typ == data.Type {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if query.Tag != "" {
+		found := false
+		for _, tag := range data.Tags {
+			if tag == query.Tag {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if query.Q != "" {
+		q := strings.ToLower(query.Q)
+		if !strings.Contains(strings.ToLower(data.Title), q) && !strings.Contains(strings.ToLower(data.Description), q) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compareItemSortValues compares the sort keys of two items in ascending order
+func compareItemSortValues(sortBy string, a, b string) int {
+	if sortBy == "title" {
+		return strings.Compare(a, b)
+	}
+
+	// Timestamps can't be compared as strings because RFC3339Nano trims trailing zeros
+	ta, _ := time.Parse(time.RFC3339Nano, a)
+	tb, _ := time.Parse(time.RFC3339Nano, b)
+
+	switch {
+	case ta.Before(tb):
+		return -1
+	case ta.After(tb):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// List fetches a page of items matching query
+func (store *MemoryItemStore) List(query ItemQuery) (page ItemPage, err error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	type entry struct {
+		item  Item
+		value string
+	}
+
+	var entries []entry
+
+	for _, item := range store.sorted() {
+		var data itemQueryData
+
+		if err = json.Unmarshal(item.Data, &data); err != nil {
+			return
+		}
+
+		if !matchesItemQuery(data, query) {
+			continue
+		}
+
+		var value string
+		if value, err = itemSortValue(item, query.Sort); err != nil {
+			return
+		}
+
+		entries = append(entries, entry{item, value})
+	}
+
+	page.Total = len(entries)
+
+	// compare orders two items by sort key and then by ID, honouring the requested order
+	compare := func(aValue string, aID int64, bValue string, bID int64) int {
+		c := compareItemSortValues(query.Sort, aValue, bValue)
+		if c == 0 {
+			switch {
+			case aID < bID:
+				c = -1
+			case aID > bID:
+				c = 1
+			}
+		}
+
+		if query.Order == "desc" {
+			c = -c
+		}
+
+		return c
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return compare(entries[i].value, entries[i].item.ID, entries[j].value, entries[j].item.ID) < 0
+	})
+
+	page.Items = make([]Item, 0)
+
+	for _, e := range entries {
+		if query.Cursor != nil && compare(e.value, e.item.ID, query.Cursor.Value, query.Cursor.ID) <= 0 {
+			continue
+		}
+
+		if len(page.Items) == query.Size {
+			page.NextCursor, err = newItemCursor(query, page.Items[len(page.Items)-1])
+			break
+		}
+
+		page.Items = append(page.Items, e.item)
+	}
+
+	return
+}
+
+// ListByType fetches all items whose data has the given "type"
+func (store *MemoryItemStore) ListByType(typ string) ([]Item, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	items := make([]Item, 0)
+
+	for _, item := range store.sorted() {
+		var itemCommonData ItemCommonData
+
+		if err := json.Unmarshal(item.Data, &itemCommonData); err != nil {
+			return nil, err
+		}
+
+		if itemCommonData.Type == typ {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// Nearby fetches the items whose coordinates are within the radius of query,
+// closest first, along with their distance
+func (store *MemoryItemStore) Nearby(query NearbyQuery) ([]NearbyItem, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	items := make([]NearbyItem, 0)
+
+	for _, item := range store.sorted() {
+		var data itemQueryData
+
+		if err := json.Unmarshal(item.Data, &data); err != nil {
+			return nil, err
+		}
+
+		if !matchesItemQuery(data, ItemQuery{Type: query.Type, Types: query.Types}) {
+			continue
+		}
+
+		// Items with missing or malformed coordinates can't be placed
+		var coordinates struct {
+			Coordinates []float64 `json:"coordinates"`
+		}
+		if err := json.Unmarshal(item.Data, &coordinates); err != nil || len(coordinates.Coordinates) != 2 {
+			continue
+		}
+
+		distance := greatCircleDistance(query.Latitude, query.Longitude, coordinates.Coordinates[0], coordinates.Coordinates[1])
+		if distance <= query.Radius {
+			items = append(items, NearbyItem{item, distance})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Distance < items[j].Distance })
+
+	if len(items) > query.Size {
+		items = items[:query.Size]
+	}
+
+	return items, nil
+}
+
+// Create inserts a new item and returns it
+func (store *MemoryItemStore) Create(data []byte) (Item, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	item := Item{
+		ID:        store.nextID,
+		Version:   1,
+		Data:      data,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	store.items[item.ID] = item
+	store.addRevision(item.ID, RevisionCreate, data)
+	store.nextID++
+
+	return item, nil
+}
+
+// live fetches an item that is not in the trash and checks its version. The caller must hold the lock.
+func (store *MemoryItemStore) live(id int64, version int64) (Item, error) {
+	item, ok := store.items[id]
+	if !ok || item.DeletedAt != nil {
+		return Item{}, ErrItemNotFound
+	}
+
+	if version != 0 && version != item.Version {
+		return Item{}, ErrVersionMismatch
+	}
+
+	return item, nil
+}
+
+// Update replaces the data of an existing item and returns it
+func (store *MemoryItemStore) Update(id int64, data []byte, version int64) (Item, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	item, err := store.live(id, version)
+	if err != nil {
+		return Item{}, err
+	}
+
+	item.Version++
+	item.Data = data
+	item.UpdatedAt = time.Now()
+	store.items[id] = item
+	store.addRevision(id, RevisionUpdate, data)
+
+	return item, nil
+}
+
+// Delete moves an item to the trash
+func (store *MemoryItemStore) Delete(id int64, version int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	item, err := store.live(id, version)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	item.Version++
+	item.DeletedAt = &now
+	store.items[id] = item
+	store.addRevision(id, RevisionDelete, item.Data)
+	return nil
+}
+
+// ListTrash fetches the items in the trash, most recently deleted first
+func (store *MemoryItemStore) ListTrash() ([]Item, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	items := make([]Item, 0)
+	for _, item := range store.items {
+		if item.DeletedAt != nil {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(*items[j].DeletedAt) {
+			return items[i].DeletedAt.After(*items[j].DeletedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	return items, nil
+}
+
+// Restore takes an item out of the trash
+func (store *MemoryItemStore) Restore(id int64) (Item, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	item, ok := store.items[id]
+	if !ok || item.DeletedAt == nil {
+		return Item{}, ErrItemNotFound
+	}
+
+	item.Version++
+	item.DeletedAt = nil
+	item.UpdatedAt = time.Now()
+	store.items[id] = item
+	store.addRevision(id, RevisionRestore, item.Data)
+
+	return item, nil
+}
+
+// Purge permanently removes the items that were moved to the trash before the given time
+func (store *MemoryItemStore) Purge(before time.Time) ([]Item, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	items := make([]Item, 0)
+	for id, item := range store.items {
+		if item.DeletedAt != nil && item.DeletedAt.Before(before) {
+			items = append(items, item)
+			delete(store.items, id)
+			delete(store.revisions, id)
+		}
+	}
+
+	return items, nil
+}
+
+// addRevision records a new revision of an item. The caller must hold the lock.
+func (store *MemoryItemStore) addRevision(id int64, action string, data []byte) {
+	revisions := store.revisions[id]
+
+	store.revisions[id] = append(revisions, ItemRevision{
+		ItemID:    id,
+		Revision:  len(revisions) + 1,
+		Action:    action,
+		Data:      data,
+		CreatedAt: time.Now(),
+	})
+}
+
+// ListRevisions fetches the revision history of an item, oldest first
+func (store *MemoryItemStore) ListRevisions(id int64) ([]ItemRevision, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	revisions, ok := store.revisions[id]
+	if !ok {
+		return nil, ErrItemNotFound
+	}
+
+	return append([]ItemRevision(nil), revisions...), nil
+}
+
+// GetRevision fetches a single revision of an item
+func (store *MemoryItemStore) GetRevision(id int64, revision int) (ItemRevision, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	revisions := store.revisions[id]
+	if revision < 1 || revision > len(revisions) {
+		return ItemRevision{}, ErrRevisionNotFound
+	}
+
+	return revisions[revision-1], nil
+}
+
+// RestoreRevision brings an item back to the data it had at a revision
+func (store *MemoryItemStore) RestoreRevision(id int64, revision int, version int64) (Item, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	revisions := store.revisions[id]
+	if revision < 1 || revision > len(revisions) {
+		return Item{}, ErrRevisionNotFound
+	}
+
+	item, ok := store.items[id]
+	if !ok {
+		return Item{}, ErrItemNotFound
+	} else if version != 0 && version != item.Version {
+		return Item{}, ErrVersionMismatch
+	}
+
+	item.Version++
+	item.Data = revisions[revision-1].Data
+	item.UpdatedAt = time.Now()
+	item.DeletedAt = nil
+	store.items[id] = item
+	store.addRevision(id, RevisionRestore, item.Data)
+
+	return item, nil
+}
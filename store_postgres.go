@@ -0,0 +1,486 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const itemColumns = "id, version, data, created_at, updated_at, deleted_at"
+
+// PostgresItemStore is an ItemStore backed by PostgreSQL.
+// It holds a single pooled connection that is shared by all requests.
+type PostgresItemStore struct {
+	db *sql.DB
+}
+
+// openPostgres opens a connection pool to the database at connStr.
+// The pool is meant to be shared by all the Postgres stores.
+func openPostgres(connStr string) (db *sql.DB, err error) {
+	if db, err = sql.Open("postgres", connStr); err != nil {
+		return
+	}
+
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		db = nil
+	}
+
+	return
+}
+
+// NewPostgresItemStore creates a PostgresItemStore that uses the connection pool db
+func NewPostgresItemStore(db *sql.DB) *PostgresItemStore {
+	return &PostgresItemStore{db: db}
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanItem reads the columns listed in itemColumns into an Item
+func scanItem(row rowScanner) (item Item, err error) {
+	err = row.Scan(
+		&item.ID,
+		&item.Version,
+		&item.Data,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.DeletedAt,
+	)
+	return
+}
+
+// queryItems runs a query that selects itemColumns and collects the results
+func (store *PostgresItemStore) queryItems(query string, args ...interface{}) (items []Item, err error) {
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	items = make([]Item, 0)
+
+	for rows.Next() {
+		var item Item
+
+		if item, err = scanItem(rows); err != nil {
+			return
+		}
+
+		items = append(items, item)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// Get fetches a single item by its ID
+func (store *PostgresItemStore) Get(id int64) (item Item, err error) {
+	item, err = scanItem(store.db.QueryRow("SELECT "+itemColumns+" FROM items WHERE id = $1 AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		err = ErrItemNotFound
+	}
+	return
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// List fetches a page of items matching query
+func (store *PostgresItemStore) List(query ItemQuery) (page ItemPage, err error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.Type != "" {
+		conditions = append(conditions, "data->>'type' = "+arg(query.Type))
+	}
+
+	if len(query.Types) > 0 {
+		conditions = append(conditions, "data->>'type' = ANY("+arg(pq.Array(query.Types))+")")
+	}
+
+	if query.Tag != "" {
+		conditions = append(conditions, "data->'tags' ? "+arg(query.Tag))
+	}
+
+	if query.Q != "" {
+		pattern := arg("%" + escapeLike(query.Q) + "%")
+		conditions = append(conditions, fmt.Sprintf("(data->>'title' ILIKE %s OR data->>'description' ILIKE %s)", pattern, pattern))
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	if err = store.db.QueryRow("SELECT COUNT(*) FROM items"+where, args...).Scan(&page.Total); err != nil {
+		return
+	}
+
+	sortExpr, cast := query.Sort, "::timestamptz"
+	if query.Sort == "title" {
+		sortExpr, cast = "COALESCE(data->>'title', '')", ""
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Order == "desc" {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s%s, %s)",
+			sortExpr, comparison, arg(query.Cursor.Value), cast, arg(query.Cursor.ID)))
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one more item than requested to find out whether there is a next page
+	page.Items, err = store.queryItems(fmt.Sprintf("SELECT %s FROM items%s ORDER BY %s %s, id %s LIMIT %s",
+		itemColumns, where, sortExpr, direction, direction, arg(query.Size+1)), args...)
+	if err != nil {
+		return
+	}
+
+	if len(page.Items) > query.Size {
+		page.Items = page.Items[:query.Size]
+		page.NextCursor, err = newItemCursor(query, page.Items[len(page.Items)-1])
+	}
+
+	return
+}
+
+// ListByType fetches all items whose data has the given "type"
+func (store *PostgresItemStore) ListByType(typ string) ([]Item, error) {
+	return store.queryItems("SELECT "+itemColumns+" FROM items WHERE data->>'type' = $1 AND deleted_at IS NULL", typ)
+}
+
+// itemEarthPoint is the point on the Earth of the coordinates of an item, as used by the earthdistance extension.
+// It matches the expression of the items_earth_idx index, which only covers the items in which itemHasCoordinates is true.
+const (
+	itemEarthPoint     = "ll_to_earth((data->'coordinates'->>0)::float8, (data->'coordinates'->>1)::float8)"
+	itemHasCoordinates = "jsonb_typeof(data->'coordinates'->0) = 'number' AND jsonb_typeof(data->'coordinates'->1) = 'number'"
+)
+
+// Nearby fetches the items whose coordinates are within the radius of query,
+// closest first, along with their distance
+func (store *PostgresItemStore) Nearby(query NearbyQuery) (items []NearbyItem, err error) {
+	var args []interface{}
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// The bounding box lets the index narrow the items down before their exact distance is computed
+	point := fmt.Sprintf("ll_to_earth(%s, %s)", arg(query.Latitude), arg(query.Longitude))
+	radius := arg(query.Radius)
+	distance := fmt.Sprintf("earth_distance(%s, %s)", point, itemEarthPoint)
+
+	conditions := []string{
+		"deleted_at IS NULL",
+		itemHasCoordinates,
+		fmt.Sprintf("earth_box(%s, %s) @> %s", point, radius, itemEarthPoint),
+		fmt.Sprintf("%s <= %s", distance, radius),
+	}
+
+	if query.Type != "" {
+		conditions = append(conditions, "data->>'type' = "+arg(query.Type))
+	}
+
+	if len(query.Types) > 0 {
+		conditions = append(conditions, "data->>'type' = ANY("+arg(pq.Array(query.Types))+")")
+	}
+
+	rows, err := store.db.Query(fmt.Sprintf("SELECT %s, %s AS distance FROM items WHERE %s ORDER BY distance, id LIMIT %s",
+		itemColumns, distance, strings.Join(conditions, " AND "), arg(query.Size)), args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	items = make([]NearbyItem, 0)
+
+	for rows.Next() {
+		var item NearbyItem
+
+		err = rows.Scan(
+			&item.ID,
+			&item.Version,
+			&item.Data,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.DeletedAt,
+			&item.Distance,
+		)
+		if err != nil {
+			return
+		}
+
+		items = append(items, item)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// withTx runs fn in a transaction, which is committed when fn succeeds
+func (store *PostgresItemStore) withTx(fn func(tx *sql.Tx) error) (err error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	err = fn(tx)
+	return
+}
+
+// addRevision records a new revision of an item.
+// The caller must have locked the item's row, or be the one creating it.
+func addRevision(tx *sql.Tx, id int64, action string, data []byte) error {
+	_, err := tx.Exec(`INSERT INTO item_revisions (item_id, revision, action, data, created_at)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, NOW() FROM item_revisions WHERE item_id = $1`,
+		id, action, data)
+	return err
+}
+
+// Create inserts a new item and returns it
+func (store *PostgresItemStore) Create(data []byte) (item Item, err error) {
+	err = store.withTx(func(tx *sql.Tx) (err error) {
+		item, err = scanItem(tx.QueryRow(
+			"INSERT INTO items (data, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING "+itemColumns,
+			data,
+		))
+		if err != nil {
+			return
+		}
+
+		return addRevision(tx, item.ID, RevisionCreate, item.Data)
+	})
+	return
+}
+
+// lockItem locks the row of an item that is not in the trash and checks its version
+func lockItem(tx *sql.Tx, id int64, version int64) error {
+	var current int64
+
+	err := tx.QueryRow("SELECT version FROM items WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrItemNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if version != 0 && version != current {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// Update replaces the data of an existing item and returns it
+func (store *PostgresItemStore) Update(id int64, data []byte, version int64) (item Item, err error) {
+	err = store.withTx(func(tx *sql.Tx) (err error) {
+		if err = lockItem(tx, id, version); err != nil {
+			return
+		}
+
+		item, err = scanItem(tx.QueryRow(
+			"UPDATE items SET data = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 RETURNING "+itemColumns,
+			data, id,
+		))
+		if err != nil {
+			return
+		}
+
+		return addRevision(tx, id, RevisionUpdate, item.Data)
+	})
+	return
+}
+
+// Delete moves an item to the trash
+func (store *PostgresItemStore) Delete(id int64, version int64) error {
+	return store.withTx(func(tx *sql.Tx) error {
+		if err := lockItem(tx, id, version); err != nil {
+			return err
+		}
+
+		var data []byte
+
+		err := tx.QueryRow("UPDATE items SET deleted_at = NOW(), version = version + 1 WHERE id = $1 RETURNING data", id).Scan(&data)
+		if err != nil {
+			return err
+		}
+
+		return addRevision(tx, id, RevisionDelete, data)
+	})
+}
+
+// ListTrash fetches the items in the trash, most recently deleted first
+func (store *PostgresItemStore) ListTrash() ([]Item, error) {
+	return store.queryItems("SELECT " + itemColumns + " FROM items WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id")
+}
+
+// Restore takes an item out of the trash
+func (store *PostgresItemStore) Restore(id int64) (item Item, err error) {
+	err = store.withTx(func(tx *sql.Tx) (err error) {
+		item, err = scanItem(tx.QueryRow(
+			"UPDATE items SET deleted_at = NULL, updated_at = NOW(), version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+itemColumns,
+			id,
+		))
+		if err == sql.ErrNoRows {
+			return ErrItemNotFound
+		} else if err != nil {
+			return
+		}
+
+		return addRevision(tx, id, RevisionRestore, item.Data)
+	})
+	return
+}
+
+// Purge permanently removes the items that were moved to the trash before the given time
+func (store *PostgresItemStore) Purge(before time.Time) (items []Item, err error) {
+	err = store.withTx(func(tx *sql.Tx) (err error) {
+		rows, err := tx.Query("DELETE FROM items WHERE deleted_at < $1 RETURNING "+itemColumns, before)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+
+		items = make([]Item, 0)
+		ids := make([]int64, 0)
+
+		for rows.Next() {
+			var item Item
+
+			if item, err = scanItem(rows); err != nil {
+				return
+			}
+
+			items = append(items, item)
+			ids = append(ids, item.ID)
+		}
+
+		if err = rows.Err(); err != nil {
+			return
+		}
+
+		_, err = tx.Exec("DELETE FROM item_revisions WHERE item_id = ANY($1)", pq.Array(ids))
+		return
+	})
+	return
+}
+
+const revisionColumns = "item_id, revision, action, data, created_at"
+
+// scanRevision reads the columns listed in revisionColumns into an ItemRevision
+func scanRevision(row rowScanner) (revision ItemRevision, err error) {
+	err = row.Scan(
+		&revision.ItemID,
+		&revision.Revision,
+		&revision.Action,
+		&revision.Data,
+		&revision.CreatedAt,
+	)
+	return
+}
+
+// ListRevisions fetches the revision history of an item, oldest first
+func (store *PostgresItemStore) ListRevisions(id int64) (revisions []ItemRevision, err error) {
+	rows, err := store.db.Query("SELECT "+revisionColumns+" FROM item_revisions WHERE item_id = $1 ORDER BY revision", id)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	revisions = make([]ItemRevision, 0)
+
+	for rows.Next() {
+		var revision ItemRevision
+
+		if revision, err = scanRevision(rows); err != nil {
+			return
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if len(revisions) == 0 {
+		err = ErrItemNotFound
+	}
+
+	return
+}
+
+// GetRevision fetches a single revision of an item
+func (store *PostgresItemStore) GetRevision(id int64, revision int) (itemRevision ItemRevision, err error) {
+	itemRevision, err = scanRevision(store.db.QueryRow(
+		"SELECT "+revisionColumns+" FROM item_revisions WHERE item_id = $1 AND revision = $2",
+		id, revision,
+	))
+	if err == sql.ErrNoRows {
+		err = ErrRevisionNotFound
+	}
+	return
+}
+
+// RestoreRevision brings an item back to the data it had at a revision
+func (store *PostgresItemStore) RestoreRevision(id int64, revision int, version int64) (item Item, err error) {
+	err = store.withTx(func(tx *sql.Tx) (err error) {
+		var data []byte
+
+		err = tx.QueryRow("SELECT data FROM item_revisions WHERE item_id = $1 AND revision = $2", id, revision).Scan(&data)
+		if err == sql.ErrNoRows {
+			return ErrRevisionNotFound
+		} else if err != nil {
+			return
+		}
+
+		// Unlike lockItem, this finds items in the trash too
+		var current int64
+		err = tx.QueryRow("SELECT version FROM items WHERE id = $1 FOR UPDATE", id).Scan(&current)
+		if err == sql.ErrNoRows {
+			return ErrItemNotFound
+		} else if err != nil {
+			return
+		}
+
+		if version != 0 && version != current {
+			return ErrVersionMismatch
+		}
+
+		item, err = scanItem(tx.QueryRow(
+			"UPDATE items SET data = $1, updated_at = NOW(), deleted_at = NULL, version = version + 1 WHERE id = $2 RETURNING "+itemColumns,
+			data, id,
+		))
+		if err != nil {
+			return
+		}
+
+		return addRevision(tx, id, RevisionRestore, item.Data)
+	})
+	return
+}
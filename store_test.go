@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryItemStoreListPagination(t *testing.T) {
+	store := NewMemoryItemStore()
+
+	for _, title := range []string{"Delta", "Alpha", "Charlie", "Bravo", "Echo"} {
+		data := fmt.Sprintf(`{"type": "location", "title": "%s", "tags": ["food"]}`, title)
+		if _, err := store.Create([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Create([]byte(`{"type": "event", "title": "Foxtrot"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	query := ItemQuery{Type: "location", Sort: "title", Order: "asc", Size: 2}
+
+	var titles []string
+	for {
+		page, err := store.List(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, 5, page.Total)
+
+		for _, item := range page.Items {
+			title, _ := itemSortValue(item, "title")
+			titles = append(titles, title)
+		}
+
+		if page.NextCursor == nil {
+			break
+		}
+
+		query.Cursor, err = decodeItemCursor(page.NextCursor.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo"}, titles)
+}
+
+func TestMemoryItemStoreListFilters(t *testing.T) {
+	store := NewMemoryItemStore()
+
+	store.Create([]byte(`{"type": "location", "title": "Noodle Bar", "tags": ["food"]}`))
+	store.Create([]byte(`{"type": "location", "title": "Museum", "description": "Old noodle machines", "tags": ["art"]}`))
+	store.Create([]byte(`{"type": "event", "title": "Noodle Festival", "tags": ["food"]}`))
+
+	page, err := store.List(ItemQuery{Q: "noodle", Tag: "food", Sort: "created_at", Order: "desc", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, int64(3), page.Items[0].ID)
+	assert.Equal(t, int64(1), page.Items[1].ID)
+	assert.Nil(t, page.NextCursor)
+}
+
+func TestMemoryItemStoreRevisions(t *testing.T) {
+	store := NewMemoryItemStore()
+
+	item, _ := store.Create([]byte(`{"title": "First"}`))
+	store.Update(item.ID, []byte(`{"title": "Second"}`), 0)
+	store.Delete(item.ID, 0)
+
+	_, err := store.Get(item.ID)
+	assert.Equal(t, ErrItemNotFound, err)
+
+	_, err = store.RestoreRevision(item.ID, 1, 1)
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	restored, err := store.RestoreRevision(item.ID, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"title": "First"}`, string(restored.Data))
+
+	revisions, err := store.ListRevisions(item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []string
+	for _, revision := range revisions {
+		actions = append(actions, revision.Action)
+	}
+	assert.Equal(t, []string{RevisionCreate, RevisionUpdate, RevisionDelete, RevisionRestore}, actions)
+
+	_, err = store.GetRevision(item.ID, 5)
+	assert.Equal(t, ErrRevisionNotFound, err)
+}
+
+func TestMemoryItemStoreTrash(t *testing.T) {
+	store := NewMemoryItemStore()
+
+	kept, _ := store.Create([]byte(`{"type": "location", "title": "Kept"}`))
+	deleted, _ := store.Create([]byte(`{"type": "location", "title": "Deleted"}`))
+
+	if err := store.Delete(deleted.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrItemNotFound, store.Delete(deleted.ID, 0))
+
+	items, _ := store.ListByType("location")
+	assert.Len(t, items, 1)
+	assert.Equal(t, kept.ID, items[0].ID)
+
+	trash, _ := store.ListTrash()
+	assert.Len(t, trash, 1)
+	assert.Equal(t, deleted.ID, trash[0].ID)
+
+	if _, err := store.Restore(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err := store.Restore(deleted.ID)
+	assert.Equal(t, ErrItemNotFound, err)
+
+	store.Delete(deleted.ID, 0)
+
+	purged, _ := store.Purge(time.Now().Add(-time.Hour))
+	assert.Empty(t, purged)
+
+	purged, _ = store.Purge(time.Now().Add(time.Second))
+	assert.Len(t, purged, 1)
+
+	_, err = store.ListRevisions(deleted.ID)
+	assert.Equal(t, ErrItemNotFound, err)
+}
+
+func TestMemoryItemStoreVersions(t *testing.T) {
+	store := NewMemoryItemStore()
+
+	item, _ := store.Create([]byte(`{"title": "First"}`))
+	assert.Equal(t, int64(1), item.Version)
+
+	updated, err := store.Update(item.ID, []byte(`{"title": "Second"}`), item.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(2), updated.Version)
+
+	_, err = store.Update(item.ID, []byte(`{"title": "Stale"}`), item.Version)
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	assert.Equal(t, ErrVersionMismatch, store.Delete(item.ID, item.Version))
+	assert.NoError(t, store.Delete(item.ID, updated.Version))
+}
+
+func TestMemoryItemStoreNearby(t *testing.T) {
+	store := NewMemoryItemStore()
+
+	store.Create([]byte(`{"type": "location", "title": "Raffles Place", "coordinates": [1.2840, 103.8514]}`))
+	store.Create([]byte(`{"type": "location", "title": "Orchard", "coordinates": [1.3048, 103.8318]}`))
+	store.Create([]byte(`{"type": "event", "title": "Marina Bay", "coordinates": [1.2816, 103.8636]}`))
+	store.Create([]byte(`{"type": "location", "title": "Changi", "coordinates": [1.3644, 103.9915]}`))
+	store.Create([]byte(`{"type": "location", "title": "Nowhere", "coordinates": "unknown"}`))
+
+	items, err := store.Nearby(NearbyQuery{Latitude: 1.2838, Longitude: 103.8591, Radius: 5000, Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []int64{3, 1, 2}, ids)
+	assert.InDelta(t, 859, items[1].Distance, 5)
+
+	items, err = store.Nearby(NearbyQuery{Latitude: 1.2838, Longitude: 103.8591, Radius: 5000, Type: "location", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, int64(1), items[0].ID)
+	}
+}
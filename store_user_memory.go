@@ -0,0 +1,161 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type memorySession struct {
+	userID    int64
+	expiresAt time.Time
+}
+
+// MemoryUserStore is a UserStore that keeps everything in memory
+type MemoryUserStore struct {
+	mu       sync.RWMutex
+	users    map[string]User
+	sessions map[string]memorySession
+	nextID   int64
+}
+
+// NewMemoryUserStore creates an empty MemoryUserStore
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users:    make(map[string]User),
+		sessions: make(map[string]memorySession),
+		nextID:   1,
+	}
+}
+
+// CreateUser adds a new user
+func (store *MemoryUserStore) CreateUser(username string, passwordHash []byte, role Role) (User, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.users[username]; ok {
+		return User{}, ErrUserExists
+	}
+
+	user := User{
+		ID:           store.nextID,
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}
+
+	store.users[username] = user
+	store.nextID++
+
+	return user, nil
+}
+
+// GetUser fetches a user by username
+func (store *MemoryUserStore) GetUser(username string) (User, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	user, ok := store.users[username]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// ListUsers fetches all users ordered by username
+func (store *MemoryUserStore) ListUsers() ([]User, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	users := make([]User, 0, len(store.users))
+	for _, user := range store.users {
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+// endSessions removes all sessions of a user. The caller must hold the lock.
+func (store *MemoryUserStore) endSessions(userID int64) {
+	for tokenHash, session := range store.sessions {
+		if session.userID == userID {
+			delete(store.sessions, tokenHash)
+		}
+	}
+}
+
+// SetPassword changes the password of a user and ends all of their sessions
+func (store *MemoryUserStore) SetPassword(username string, passwordHash []byte) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	user, ok := store.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	user.PasswordHash = passwordHash
+	store.users[username] = user
+	store.endSessions(user.ID)
+
+	return nil
+}
+
+// DeleteUser removes a user and all of their sessions
+func (store *MemoryUserStore) DeleteUser(username string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	user, ok := store.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	delete(store.users, username)
+	store.endSessions(user.ID)
+
+	return nil
+}
+
+// CreateSession starts a session for a user
+func (store *MemoryUserStore) CreateSession(userID int64, tokenHash []byte, expiresAt time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.sessions[string(tokenHash)] = memorySession{userID, expiresAt}
+	return nil
+}
+
+// GetSessionUser fetches the user of a session that has not expired yet
+func (store *MemoryUserStore) GetSessionUser(tokenHash []byte) (User, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	session, ok := store.sessions[string(tokenHash)]
+	if !ok || !time.Now().Before(session.expiresAt) {
+		return User{}, ErrSessionNotFound
+	}
+
+	for _, user := range store.users {
+		if user.ID == session.userID {
+			return user, nil
+		}
+	}
+
+	return User{}, ErrSessionNotFound
+}
+
+// DeleteSession ends a session
+func (store *MemoryUserStore) DeleteSession(tokenHash []byte) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.sessions, string(tokenHash))
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const userColumns = "id, username, password_hash, role, created_at"
+
+// PostgresUserStore is a UserStore backed by PostgreSQL
+type PostgresUserStore struct {
+	db *sql.DB
+}
+
+// NewPostgresUserStore creates a PostgresUserStore that uses the connection pool db
+func NewPostgresUserStore(db *sql.DB) *PostgresUserStore {
+	return &PostgresUserStore{db: db}
+}
+
+// scanUser reads the columns listed in userColumns into a User
+func scanUser(row rowScanner) (user User, err error) {
+	err = row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		err = ErrUserNotFound
+	}
+	return
+}
+
+// CreateUser adds a new user
+func (store *PostgresUserStore) CreateUser(username string, passwordHash []byte, role Role) (user User, err error) {
+	user, err = scanUser(store.db.QueryRow(
+		"INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3) RETURNING "+userColumns,
+		username, string(passwordHash), string(role),
+	))
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		err = ErrUserExists
+	}
+	return
+}
+
+// GetUser fetches a user by username
+func (store *PostgresUserStore) GetUser(username string) (User, error) {
+	return scanUser(store.db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = $1", username))
+}
+
+// ListUsers fetches all users ordered by username
+func (store *PostgresUserStore) ListUsers() (users []User, err error) {
+	rows, err := store.db.Query("SELECT " + userColumns + " FROM users ORDER BY username")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	users = make([]User, 0)
+
+	for rows.Next() {
+		var user User
+
+		if user, err = scanUser(rows); err != nil {
+			return
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	return
+}
+
+// SetPassword changes the password of a user and ends all of their sessions
+func (store *PostgresUserStore) SetPassword(username string, passwordHash []byte) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("UPDATE users SET password_hash = $1 WHERE username = $2 RETURNING id", string(passwordHash), username).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM sessions WHERE user_id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUser removes a user and all of their sessions
+func (store *PostgresUserStore) DeleteUser(username string) error {
+	result, err := store.db.Exec("DELETE FROM users WHERE username = $1", username)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CreateSession starts a session for a user
+func (store *PostgresUserStore) CreateSession(userID int64, tokenHash []byte, expiresAt time.Time) error {
+	// Clean up expired sessions while we're at it
+	if _, err := store.db.Exec("DELETE FROM sessions WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+
+	_, err := store.db.Exec("INSERT INTO sessions (token_hash, user_id, expires_at) VALUES ($1, $2, $3)", tokenHash, userID, expiresAt)
+	return err
+}
+
+// GetSessionUser fetches the user of a session that has not expired yet
+func (store *PostgresUserStore) GetSessionUser(tokenHash []byte) (user User, err error) {
+	user, err = scanUser(store.db.QueryRow(
+		`SELECT users.id, users.username, users.password_hash, users.role, users.created_at
+		FROM sessions JOIN users ON users.id = sessions.user_id
+		WHERE sessions.token_hash = $1 AND sessions.expires_at > NOW()`,
+		tokenHash,
+	))
+	if err == ErrUserNotFound {
+		err = ErrSessionNotFound
+	}
+	return
+}
+
+// DeleteSession ends a session
+func (store *PostgresUserStore) DeleteSession(tokenHash []byte) error {
+	_, err := store.db.Exec("DELETE FROM sessions WHERE token_hash = $1", tokenHash)
+	return err
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeLocation returns the time zone that the opening hours of the location are in,
+// or fallback when the location has no time zone
+func (location *Location) TimeLocation(fallback *time.Location) (*time.Location, error) {
+	if location.TimeZone == "" {
+		return fallback, nil
+	} else if location.TimeZone == "Local" {
+		return nil, fmt.Errorf("time zone must be an IANA time zone")
+	}
+
+	return time.LoadLocation(location.TimeZone)
+}
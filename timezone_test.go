@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationTimeLocation(t *testing.T) {
+	location := Location{TimeZone: "Europe/Dublin"}
+	loc, err := location.TimeLocation(time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Dublin", loc.String())
+
+	// Coordinates alone don't tell the time zone
+	location = Location{Coordinates: []float64{51.5072, -0.1276}}
+	loc, err = location.TimeLocation(time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUserNotFound is returned by a UserStore when the requested user does not exist
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserExists is returned by a UserStore when a username is already taken
+	ErrUserExists = errors.New("user already exists")
+
+	// ErrSessionNotFound is returned by a UserStore when a session does not exist or has expired
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Role decides what a user is allowed to do.
+// Each role includes the permissions of the roles before it.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"    // can read items
+	RoleEditor    Role = "editor"    // can create, update and delete items
+	RolePublisher Role = "publisher" // can generate the site
+	RoleAdmin     Role = "admin"     // can do everything
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:    1,
+	RoleEditor:    2,
+	RolePublisher: 3,
+	RoleAdmin:     4,
+}
+
+// parseRole checks that s names a known role
+func parseRole(s string) (role Role, err error) {
+	role = Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		err = fmt.Errorf("unknown role \"%s\", must be one of viewer, editor, publisher or admin", s)
+	}
+	return
+}
+
+// Includes reports whether the role has at least the permissions of other
+func (role Role) Includes(other Role) bool {
+	return roleRanks[role] >= roleRanks[other]
+}
+
+// User is an account that can sign in to the administration API
+type User struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"-"`
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// UserStore is the storage backend for user accounts and their sessions.
+// Sessions are looked up by the SHA-256 hash of their token, so that the
+// tokens themselves are never stored.
+type UserStore interface {
+	// CreateUser adds a new user
+	CreateUser(username string, passwordHash []byte, role Role) (User, error)
+
+	// GetUser fetches a user by username
+	GetUser(username string) (User, error)
+
+	// ListUsers fetches all users ordered by username
+	ListUsers() ([]User, error)
+
+	// SetPassword changes the password of a user and ends all of their sessions
+	SetPassword(username string, passwordHash []byte) error
+
+	// DeleteUser removes a user and all of their sessions
+	DeleteUser(username string) error
+
+	// CreateSession starts a session for a user
+	CreateSession(userID int64, tokenHash []byte, expiresAt time.Time) error
+
+	// GetSessionUser fetches the user of a session that has not expired yet
+	GetSessionUser(tokenHash []byte) (User, error)
+
+	// DeleteSession ends a session
+	DeleteSession(tokenHash []byte) error
+}
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.@-]{1,64}$`)
+
+// validateUsername checks that a username is safe to display and type on the command line
+func validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("username must be 1 to 64 letters, digits or any of _.@-")
+	}
+	return nil
+}
+
+// hashPassword hashes a password with bcrypt
+func hashPassword(password string) ([]byte, error) {
+	if len(password) < 8 {
+		return nil, errors.New("password must be at least 8 characters long")
+	}
+
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// dummyPasswordHash is compared against when a user doesn't exist,
+// so that signing in takes as long for unknown users as for known ones
+var dummyPasswordHash = []byte("$2a$10$UnRF8pz4ca5WgP50kJaHuOxOk2ZwvisRRbe.Rvw0waKFj7uhI/LQ6")
+
+// checkPassword reports whether password matches the bcrypt hash
+func checkPassword(hash []byte, password string) bool {
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// newToken creates a random token and returns it along with the hash that is stored
+func newToken() (token string, tokenHash []byte, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	tokenHash = hashToken(token)
+	return
+}
+
+// hashToken hashes a session token for storage and lookup
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+)
+
+// openUserStore opens the Postgres user store for the user commands
+func openUserStore() (store *PostgresUserStore, close func() error, err error) {
+	db, err := openPostgres(dbConnStr)
+	if err != nil {
+		return
+	}
+
+	return NewPostgresUserStore(db), db.Close, nil
+}
+
+// usernameArg reads the username given as the first argument of a command
+func usernameArg(c *cli.Context) (string, error) {
+	username := c.Args().First()
+	if username == "" {
+		return "", errors.New("username is required")
+	}
+
+	return username, validateUsername(username)
+}
+
+// readPassword asks for a new password, or reads it from stdin when --password-stdin is set
+func readPassword(c *cli.Context) (passwordHash []byte, err error) {
+	var password string
+
+	if c.Bool("password-stdin") {
+		password, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			return
+		}
+
+		password = strings.TrimRight(password, "\r\n")
+	} else {
+		var confirmation string
+
+		if err = survey.AskOne(&survey.Password{Message: "Password:"}, &password); err != nil {
+			return
+		}
+
+		if err = survey.AskOne(&survey.Password{Message: "Confirm password:"}, &confirmation); err != nil {
+			return
+		}
+
+		if password != confirmation {
+			err = errors.New("passwords do not match")
+			return
+		}
+	}
+
+	return hashPassword(password)
+}
+
+func userAddCommand(c *cli.Context) error {
+	username, err := usernameArg(c)
+	if err != nil {
+		return err
+	}
+
+	role, err := parseRole(c.String("role"))
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := readPassword(c)
+	if err != nil {
+		return err
+	}
+
+	store, close, err := openUserStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	if _, err = store.CreateUser(username, passwordHash, role); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added user \"%s\" with the %s role\n", username, role)
+	return nil
+}
+
+func userListCommand(c *cli.Context) error {
+	store, close, err := openUserStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	users, err := store.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tROLE\tCREATED AT")
+
+	for _, user := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", user.Username, user.Role, user.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func userPasswdCommand(c *cli.Context) error {
+	username, err := usernameArg(c)
+	if err != nil {
+		return err
+	}
+
+	store, close, err := openUserStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	// Fail before asking for a password when the user doesn't exist
+	if _, err = store.GetUser(username); err != nil {
+		return err
+	}
+
+	passwordHash, err := readPassword(c)
+	if err != nil {
+		return err
+	}
+
+	if err = store.SetPassword(username, passwordHash); err != nil {
+		return err
+	}
+
+	fmt.Printf("Changed the password of \"%s\" and signed them out everywhere\n", username)
+	return nil
+}
+
+func userRemoveCommand(c *cli.Context) error {
+	username, err := usernameArg(c)
+	if err != nil {
+		return err
+	}
+
+	store, close, err := openUserStore()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	if err = store.DeleteUser(username); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed user \"%s\"\n", username)
+	return nil
+}
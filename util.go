@@ -1,26 +1,45 @@
 package main
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"crypto/sha1"
 
 	"github.com/BurntSushi/toml"
 )
 
-// generateLocationContent generates static-site content for Location page to be used by Zola
-func generateLocationContent(location Location) error {
-	zolaLocation, err := location.Zola()
+// generateLocationContent generates static-site content for Location page to be used by Zola.
+// now is the time the site is generated at.
+func generateLocationContent(location Location, now time.Time) error {
+	zolaLocation, err := location.Zola(now)
 	if err != nil {
 		return err
 	}
 
+	// Copy the cover image and its resized variants to Zola
+	if zolaLocation.Extra.CoverImage, err = publishImage(location.CoverImageURL, zolaLocation.Extra.CoverImageURL); err != nil {
+		return err
+	}
+
+	for i, imageURL := range location.ImageURLs {
+		image, err := publishImage(imageURL, zolaLocation.Extra.ImageURLs[i])
+		if err != nil {
+			return err
+		}
+
+		zolaLocation.Extra.Images = append(zolaLocation.Extra.Images, image)
+	}
+
 	filePath := fmt.Sprintf("%s/content/locations/%d.md", zolaPath, location.ID)
 
 	output, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
@@ -46,39 +65,89 @@ func generateLocationContent(location Location) error {
 		return err
 	}
 
-	// Create the cover image directory in Zola if it doesn't exist
-	coverImageDirPath := fmt.Sprintf("%s/static/img/cover/location", zolaPath)
-	if err := os.MkdirAll(coverImageDirPath, 0700); err != nil {
+	return nil
+}
+
+const (
+	eventPagesSeries     = "series"     // one page per event, listing the upcoming occurrences of recurring events
+	eventPagesOccurrence = "occurrence" // one page per upcoming occurrence of recurring events
+)
+
+var (
+	eventPages        = eventPagesSeries    // How pages of recurring events are generated unless requested otherwise
+	occurrenceHorizon = 90 * 24 * time.Hour // How far ahead occurrences of recurring events are published
+)
+
+// generateEventContent generates static-site content for Event page to be used by Zola.
+// Recurring events get either a single page for the series or a page for each upcoming occurrence,
+// depending on pages.
+func generateEventContent(event Event, pages string) error {
+	zolaEvent, err := event.Zola()
+	if err != nil {
 		return err
 	}
 
-	// Read the cover image file
-	coverImageData, err := ioutil.ReadFile("files/" + location.CoverImageURL)
-	if err != nil {
+	// Copy the cover image and its resized variants to Zola
+	if zolaEvent.Extra.CoverImage, err = publishImage(event.CoverImageURL, zolaEvent.Extra.CoverImageURL); err != nil {
 		return err
 	}
 
-	// Write the cover image file to Zola
-	coverImagePath := fmt.Sprintf("%s/static%s", zolaPath, zolaLocation.Extra.CoverImageURL)
-	if err = ioutil.WriteFile(coverImagePath, coverImageData, 0600); err != nil {
+	for i, imageURL := range event.ImageURLs {
+		image, err := publishImage(imageURL, zolaEvent.Extra.ImageURLs[i])
+		if err != nil {
+			return err
+		}
+
+		zolaEvent.Extra.Images = append(zolaEvent.Extra.Images, image)
+	}
+
+	// Occurrences that have passed or were removed from the series must not stay on the site
+	if err = removeOccurrencePages(event.ID); err != nil {
 		return err
 	}
 
-	// Create the images directory in Zola if it doesn't exist
-	imagesDirPath := fmt.Sprintf("%s/static/img/location/%d", zolaPath, location.ID)
-	if err := os.MkdirAll(imagesDirPath, 0700); err != nil {
+	// Let visitors add the event to their calendar
+	if err = writeEventCalendar(event); err != nil {
 		return err
 	}
 
-	for i, imageURL := range location.ImageURLs {
-		imageData, err := ioutil.ReadFile("files/" + imageURL)
-		if err != nil {
-			return err
+	seriesPath := fmt.Sprintf("%s/content/events/%d.md", zolaPath, event.ID)
+	if !event.Recurs() {
+		return writeEventPage(seriesPath, zolaEvent, event.Description)
+	}
+
+	now := time.Now()
+	occurrences, err := event.Occurrences(now, now.Add(occurrenceHorizon))
+	if err != nil {
+		return err
+	}
+
+	for _, occurrence := range occurrences {
+		zolaEvent.Extra.Occurrences = append(zolaEvent.Extra.Occurrences, zolaEventSession(occurrence, event.AllDay))
+	}
+
+	if pages != eventPagesOccurrence {
+		return writeEventPage(seriesPath, zolaEvent, event.Description)
+	}
+
+	if err = os.Remove(seriesPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i, occurrence := range occurrences {
+		page := zolaEvent
+		page.Extra.StartsAt = page.Extra.Occurrences[i].StartsAt
+		page.Extra.EndsAt = page.Extra.Occurrences[i].EndsAt
+		page.Extra.StartDate = page.Extra.Occurrences[i].StartDate
+		page.Extra.EndDate = page.Extra.Occurrences[i].EndDate
+
+		layout := "20060102-1504"
+		if event.AllDay {
+			layout = "20060102"
 		}
 
-		// Copy the image file
-		imagePath := fmt.Sprintf("%s/static%s", zolaPath, zolaLocation.Extra.ImageURLs[i])
-		if err = ioutil.WriteFile(imagePath, imageData, 0600); err != nil {
+		pagePath := fmt.Sprintf("%s/content/events/%d-%s.md", zolaPath, event.ID, occurrence.Start.Format(layout))
+		if err = writeEventPage(pagePath, page, event.Description); err != nil {
 			return err
 		}
 	}
@@ -86,15 +155,8 @@ func generateLocationContent(location Location) error {
 	return nil
 }
 
-// generateEventContent generates static-site content for Event page to be used by Zola
-func generateEventContent(event Event) error {
-	zolaEvent, err := event.Zola()
-	if err != nil {
-		return err
-	}
-
-	filePath := fmt.Sprintf("%s/content/events/%d.md", zolaPath, event.ID)
-
+// writeEventPage writes a page of an event with zolaEvent as its front matter
+func writeEventPage(filePath string, zolaEvent ZolaEvent, description string) error {
 	output, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
 	if err != nil {
 		return err
@@ -114,43 +176,42 @@ func generateEventContent(event Event) error {
 		return err
 	}
 
-	if _, err = output.Write([]byte(event.Description)); err != nil {
+	if _, err = output.Write([]byte(description)); err != nil {
 		return err
 	}
 
-	// Create the cover image directory in Zola if it doesn't exist
-	coverImageDirPath := fmt.Sprintf("%s/static/img/cover/event", zolaPath)
-	if err := os.MkdirAll(coverImageDirPath, 0700); err != nil {
-		return err
+	return nil
+}
+
+// writeEventCalendar writes an iCalendar file of an event next to its page.
+// Events without a schedule can't be put in a calendar, so they don't get one.
+func writeEventCalendar(event Event) error {
+	filePath := fmt.Sprintf("%s/content/events/%d.ics", zolaPath, event.ID)
+
+	if event.StartsAt == "" && len(event.Sessions) == 0 {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
 	}
 
-	// Read the cover image file
-	coverImageData, err := ioutil.ReadFile("files/" + event.CoverImageURL)
+	data, err := eventCalendar(event.Title, []Event{event}, time.Now())
 	if err != nil {
 		return err
 	}
 
-	// Write the cover image file to Zola
-	coverImagePath := fmt.Sprintf("%s/static%s", zolaPath, zolaEvent.Extra.CoverImageURL)
-	if err = ioutil.WriteFile(coverImagePath, coverImageData, 0600); err != nil {
-		return err
-	}
+	return ioutil.WriteFile(filePath, data, 0600)
+}
 
-	// Create the images directory in Zola if it doesn't exist
-	imagesDirPath := fmt.Sprintf("%s/static/img/event/%d", zolaPath, event.ID)
-	if err := os.MkdirAll(imagesDirPath, 0700); err != nil {
+// removeOccurrencePages removes the pages generated for the occurrences of a recurring event
+func removeOccurrencePages(id int64) error {
+	pagePaths, err := filepath.Glob(fmt.Sprintf("%s/content/events/%d-*.md", zolaPath, id))
+	if err != nil {
 		return err
 	}
 
-	for i, imageURL := range event.ImageURLs {
-		imageData, err := ioutil.ReadFile("files/" + imageURL)
-		if err != nil {
-			return err
-		}
-
-		// Copy the image file
-		imagePath := fmt.Sprintf("%s/static%s", zolaPath, zolaEvent.Extra.ImageURLs[i])
-		if err = ioutil.WriteFile(imagePath, imageData, 0600); err != nil {
+	for _, pagePath := range pagePaths {
+		if err := os.Remove(pagePath); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
@@ -158,8 +219,10 @@ func generateEventContent(event Event) error {
 	return nil
 }
 
-// storeImages stores base64 images from data into the filesystem
-// and replaces the image data with SHA1 checksum in the map
+// storeImages stores base64 images from data into blobStore
+// and replaces the image data with SHA1 checksum in the map.
+// It returns errImageType when an image is not in one of the allowed formats,
+// and errImageDimensions when it has too many pixels.
 func storeImages(data map[string]interface{}) (err error) {
 	var imageHash string
 
@@ -207,30 +270,37 @@ func storeImage(data map[string]interface{}, k, v string) (imageHash string, err
 		base64Data := v[i+len(";base64,"):]
 		hash.Write([]byte(base64Data))
 
-		if err = os.MkdirAll("files", 0700); err != nil {
+		var imageData []byte
+		if imageData, err = base64.StdEncoding.DecodeString(base64Data); err != nil {
 			return
 		}
 
-		var imageData []byte
-		if imageData, err = base64.StdEncoding.DecodeString(base64Data); err != nil {
+		// Trust the content over the type declared in the data URI
+		contentType := detectImageType(imageData)
+		if _, ok := imageExtensions[contentType]; !ok {
+			err = errImageType
+			return
+		}
+
+		if imageData, contentType, err = stripImageMetadata(imageData, contentType); err != nil {
 			return
 		}
 
 		imageHash = base64.StdEncoding.EncodeToString(hash.Sum(nil))
 		imageHash = strings.ReplaceAll(imageHash, "/", "_")
 
-		filename := "files/" + imageHash
-		if _, err = os.Stat(filename); err != nil {
-			if err == os.ErrExist {
-				return
-			}
-			err = nil
-		}
-
-		if err = ioutil.WriteFile(filename, imageData, 0600); err != nil {
+		var metadata map[string]string
+		if metadata, err = describeImage(imageData, contentType); err != nil {
 			return
 		}
 
+		err = blobStore.Put(BlobInfo{
+			Key:         imageHash,
+			Size:        int64(len(imageData)),
+			ContentType: contentType,
+			Metadata:    metadata,
+		}, bytes.NewReader(imageData))
+
 		return
 	} else {
 		imageHash = v
@@ -352,3 +422,106 @@ func parseOpeningHour(s string) (openingHour LocationOpeningHour, err error) {
 
 	return
 }
+
+// parseWeekday parse an English day name such as "monday" or "Mon"
+func parseWeekday(s string) (weekday time.Weekday, err error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			weekday = d
+			return
+		}
+	}
+
+	err = fmt.Errorf("\"%s\" is not a day of the week", s)
+	return
+}
+
+// removeGeneratedContent removes the page and images generated for an item from the Zola directory.
+// Cover images are kept when another item of the same type still uses them.
+func removeGeneratedContent(item Item) error {
+	if zolaPath == "" {
+		return nil
+	}
+
+	// Paths are found from the item data alone, since the images it refers to may be gone from the blob store
+	var data struct {
+		Type          string `json:"type"`
+		CoverImageURL string `json:"coverImageURL"`
+	}
+	if err := json.Unmarshal(item.Data, &data); err != nil {
+		return err
+	}
+
+	var section string
+
+	switch data.Type {
+	case "location":
+		section = "locations"
+	case "event":
+		section = "events"
+	default:
+		return nil
+	}
+
+	imageDir := fmt.Sprintf("%s/static/img/%s/%d", zolaPath, data.Type, item.ID)
+	coverImageID := data.CoverImageURL
+
+	pagePath := fmt.Sprintf("%s/content/%s/%d.md", zolaPath, section, item.ID)
+	if err := os.Remove(pagePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if section == "events" {
+		if err := removeOccurrencePages(item.ID); err != nil {
+			return err
+		}
+
+		calendarPath := fmt.Sprintf("%s/content/events/%d.ics", zolaPath, item.ID)
+		if err := os.Remove(calendarPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(imageDir); err != nil {
+		return err
+	}
+
+	if coverImageID == "" {
+		return nil
+	}
+
+	items, err := itemStore.ListByType(data.Type)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range items {
+		var otherData struct {
+			CoverImageURL string `json:"coverImageURL"`
+		}
+
+		if other.ID != item.ID && json.Unmarshal(other.Data, &otherData) == nil && otherData.CoverImageURL == coverImageID {
+			return nil
+		}
+	}
+
+	// Remove the cover image, whatever its extension, and the resized variants published next to it
+	coverImagePath := fmt.Sprintf("%s/static/img/cover/%s/%s", zolaPath, data.Type, coverImageID)
+	for _, pattern := range []string{coverImagePath + ".*", coverImagePath + "-*"} {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldError describes why a single field of an item is not valid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when item data doesn't match the schema of its type
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (err *ValidationError) Error() string {
+	var messages []string
+	for _, fieldError := range err.Errors {
+		messages = append(messages, fieldError.Field+": "+fieldError.Message)
+	}
+
+	return "item data is not valid: " + strings.Join(messages, "; ")
+}
+
+// add records an error for field
+func (err *ValidationError) add(field, format string, args ...interface{}) {
+	err.Errors = append(err.Errors, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	stringArrayField
+	numberArrayField
+	stringMapField
+	boolField
+	objectArrayField
+)
+
+func (kind fieldKind) String() string {
+	switch kind {
+	case stringField:
+		return "a string"
+	case stringArrayField:
+		return "an array of strings"
+	case numberArrayField:
+		return "an array of numbers"
+	case stringMapField:
+		return "an object of strings"
+	case boolField:
+		return "a boolean"
+	case objectArrayField:
+		return "an array of objects"
+	default:
+		return "unknown"
+	}
+}
+
+// fieldSchema describes a single field of item data.
+// Check is called only when the value is of the right kind.
+type fieldSchema struct {
+	Kind     fieldKind
+	Required bool
+	Check    func(verr *ValidationError, field string, value interface{})
+}
+
+// itemSchema maps the JSON field names of an item type to their schema
+type itemSchema map[string]fieldSchema
+
+// itemSchemas holds the schema of every item type that can be stored
+var itemSchemas = map[string]itemSchema{
+	"location": {
+		"type":          {Kind: stringField, Required: true},
+		"title":         {Kind: stringField, Required: true, Check: checkNotEmpty},
+		"description":   {Kind: stringField},
+		"address":       {Kind: stringField},
+		"coordinates":   {Kind: numberArrayField, Check: checkCoordinates},
+		"phone":         {Kind: stringField},
+		"websiteURL":    {Kind: stringField, Check: checkWebsiteURL},
+		"coverImageURL": {Kind: stringField, Required: true, Check: checkImageReference},
+		"imageURLs":     {Kind: stringArrayField, Check: checkImageReferences},
+		"tags":          {Kind: stringArrayField},
+		"openingHours":  {Kind: stringMapField, Check: checkOpeningHours},
+		"status":        {Kind: stringField, Check: checkLocationStatus},
+		"timeZone":      {Kind: stringField, Check: checkTimeZone},
+
+		// openingHoursOSM replaces openingHours and publicHolidayHours once the item is valid
+		"openingHoursOSM":    {Kind: stringField, Check: checkOSMOpeningHours},
+		"publicHolidayHours": {Kind: stringField, Check: checkPublicHolidayHours},
+
+		"openingHoursExceptions": {Kind: objectArrayField, Check: checkOpeningHoursExceptions},
+		"seasonalOpeningHours":   {Kind: objectArrayField, Check: checkSeasonalOpeningHours},
+	},
+	"event": {
+		"type":          {Kind: stringField, Required: true},
+		"title":         {Kind: stringField, Required: true, Check: checkNotEmpty},
+		"description":   {Kind: stringField},
+		"address":       {Kind: stringField},
+		"coordinates":   {Kind: numberArrayField, Check: checkCoordinates},
+		"phone":         {Kind: stringField},
+		"websiteURL":    {Kind: stringField, Check: checkWebsiteURL},
+		"coverImageURL": {Kind: stringField, Required: true, Check: checkImageReference},
+		"imageURLs":     {Kind: stringArrayField, Check: checkImageReferences},
+		"tags":          {Kind: stringArrayField},
+		"startsAt":      {Kind: stringField},
+		"endsAt":        {Kind: stringField},
+		"timeZone":      {Kind: stringField, Check: checkTimeZone},
+		"allDay":        {Kind: boolField},
+		"sessions":      {Kind: objectArrayField, Check: checkObjects(sessionSchema)},
+		"rrule":         {Kind: stringField},
+		"exDates":       {Kind: stringArrayField},
+		"rDates":        {Kind: stringArrayField},
+	},
+}
+
+// sessionSchema describes a session of an event
+var sessionSchema = itemSchema{
+	"title":    {Kind: stringField},
+	"startsAt": {Kind: stringField, Required: true},
+	"endsAt":   {Kind: stringField},
+}
+
+// openingHoursExceptionSchema describes a day or range of days on which a location has other opening hours
+var openingHoursExceptionSchema = itemSchema{
+	"date":    {Kind: stringField, Required: true, Check: checkDate},
+	"endDate": {Kind: stringField, Check: checkDate},
+	"hours":   {Kind: stringField, Check: checkHours},
+	"closed":  {Kind: boolField},
+	"note":    {Kind: stringField},
+}
+
+// seasonalOpeningHoursSchema describes a season in which a location has other weekly opening hours
+var seasonalOpeningHoursSchema = itemSchema{
+	"name":         {Kind: stringField},
+	"from":         {Kind: stringField, Required: true},
+	"to":           {Kind: stringField, Required: true},
+	"openingHours": {Kind: stringMapField, Required: true, Check: checkOpeningHours},
+}
+
+// itemChecks holds checks of item types that involve more than one field.
+// They are called after the fields have been checked on their own.
+var itemChecks = map[string]func(verr *ValidationError, data map[string]interface{}){
+	"event":    checkEventSchedule,
+	"location": checkLocationTimeZone,
+}
+
+// readOnlyFields are returned with items but set by the server.
+// Clients may send them back, and they are dropped from item data before it is checked.
+var readOnlyFields = []string{"id", "createdAt", "updatedAt"}
+
+// stripReadOnlyFields removes readOnlyFields from item data
+func stripReadOnlyFields(data map[string]interface{}) {
+	for _, field := range readOnlyFields {
+		delete(data, field)
+	}
+}
+
+// validateItemData checks data against the schema of its "type".
+// It returns a *ValidationError listing every problem that was found.
+func validateItemData(data map[string]interface{}) error {
+	verr := &ValidationError{}
+
+	typ, _ := data["type"].(string)
+	schema, ok := itemSchemas[typ]
+	if !ok {
+		if typ == "" {
+			verr.add("type", "is required")
+		} else {
+			verr.add("type", "unknown item type \"%s\"", typ)
+		}
+		return verr
+	}
+
+	checkFields(verr, "", data, schema)
+
+	if check, ok := itemChecks[typ]; ok {
+		check(verr, data)
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// checkFields checks every field of a decoded JSON object against schema.
+// prefix is put in front of the names of the fields in errors.
+func checkFields(verr *ValidationError, prefix string, data map[string]interface{}, schema itemSchema) {
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		value := data[field]
+
+		fieldSchema, ok := schema[field]
+		if !ok {
+			verr.add(prefix+field, "unknown field%s", suggestField(schema, field))
+			continue
+		}
+
+		// Treat explicit nulls as if the field was left out
+		if value == nil {
+			continue
+		}
+
+		if !isFieldKind(value, fieldSchema.Kind) {
+			verr.add(prefix+field, "must be %s", fieldSchema.Kind)
+			continue
+		}
+
+		if fieldSchema.Check != nil {
+			fieldSchema.Check(verr, prefix+field, value)
+		}
+	}
+
+	required := make([]string, 0)
+	for field, fieldSchema := range schema {
+		if fieldSchema.Required && data[field] == nil {
+			required = append(required, field)
+		}
+	}
+	sort.Strings(required)
+
+	for _, field := range required {
+		verr.add(prefix+field, "is required")
+	}
+}
+
+// checkObjects returns a Check for arrays of objects that checks each object against schema
+func checkObjects(schema itemSchema) func(verr *ValidationError, field string, value interface{}) {
+	return func(verr *ValidationError, field string, value interface{}) {
+		for i, v := range value.([]interface{}) {
+			checkFields(verr, fmt.Sprintf("%s[%d].", field, i), v.(map[string]interface{}), schema)
+		}
+	}
+}
+
+// suggestField returns a hint when field differs from a known field only by case
+func suggestField(schema itemSchema, field string) string {
+	for known := range schema {
+		if strings.EqualFold(known, field) {
+			return fmt.Sprintf(", did you mean \"%s\"?", known)
+		}
+	}
+
+	return ""
+}
+
+// isFieldKind reports whether a decoded JSON value is of the given kind
+func isFieldKind(value interface{}, kind fieldKind) bool {
+	switch kind {
+	case stringField:
+		_, ok := value.(string)
+		return ok
+	case stringArrayField, numberArrayField:
+		values, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, v := range values {
+			if kind == stringArrayField {
+				if _, ok := v.(string); !ok {
+					return false
+				}
+			} else if _, ok := v.(float64); !ok {
+				return false
+			}
+		}
+
+		return true
+	case stringMapField:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, v := range values {
+			if _, ok := v.(string); !ok {
+				return false
+			}
+		}
+
+		return true
+	case boolField:
+		_, ok := value.(bool)
+		return ok
+	case objectArrayField:
+		values, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+
+		for _, v := range values {
+			if _, ok := v.(map[string]interface{}); !ok {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func checkNotEmpty(verr *ValidationError, field string, value interface{}) {
+	if strings.TrimSpace(value.(string)) == "" {
+		verr.add(field, "must not be empty")
+	}
+}
+
+func checkCoordinates(verr *ValidationError, field string, value interface{}) {
+	coordinates := value.([]interface{})
+	if len(coordinates) != 2 {
+		verr.add(field, "must contain exactly a latitude and a longitude")
+		return
+	}
+
+	if latitude := coordinates[0].(float64); latitude < -90 || latitude > 90 {
+		verr.add(field, "latitude must be between -90 and 90")
+	}
+
+	if longitude := coordinates[1].(float64); longitude < -180 || longitude > 180 {
+		verr.add(field, "longitude must be between -180 and 180")
+	}
+}
+
+func checkWebsiteURL(verr *ValidationError, field string, value interface{}) {
+	s := value.(string)
+	if s == "" {
+		return
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		verr.add(field, "must be an absolute http or https URL")
+	}
+}
+
+func checkImageReference(verr *ValidationError, field string, value interface{}) {
+	s := value.(string)
+
+	if strings.HasPrefix(s, "data:") {
+		i := strings.Index(s, ";base64,")
+		if !strings.HasPrefix(s, "data:image/") || i < 0 {
+			verr.add(field, "must be a base64 encoded image data URI")
+		} else if _, ok := imageExtensions[s[len("data:"):i]]; !ok {
+			verr.add(field, "must be a JPEG, PNG, GIF, WebP or SVG image")
+		}
+		return
+	}
+
+	if s == "" || strings.ContainsAny(s, "/:") {
+		verr.add(field, "must be an image data URI or the ID of a stored image")
+	}
+}
+
+func checkImageReferences(verr *ValidationError, field string, value interface{}) {
+	for i, v := range value.([]interface{}) {
+		checkImageReference(verr, fmt.Sprintf("%s[%d]", field, i), v)
+	}
+}
+
+// checkStoredImages checks that the images of item data given by ID are stored in blobs.
+// It returns a *ValidationError listing the IDs that are unknown, or the error of a failed lookup.
+func checkStoredImages(data map[string]interface{}, blobs BlobStore) error {
+	verr := &ValidationError{}
+
+	check := func(field string, value interface{}) error {
+		key, ok := value.(string)
+		if !ok || strings.HasPrefix(key, "data:") {
+			return nil
+		}
+
+		if _, err := blobs.Stat(key); err == ErrBlobNotFound {
+			verr.add(field, "no image is stored with the ID \"%s\"", key)
+		} else if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if err := check("coverImageURL", data["coverImageURL"]); err != nil {
+		return err
+	}
+
+	images, _ := data["imageURLs"].([]interface{})
+	for i, v := range images {
+		if err := check(fmt.Sprintf("imageURLs[%d]", i), v); err != nil {
+			return err
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func checkOpeningHours(verr *ValidationError, field string, value interface{}) {
+	openingHours := value.(map[string]interface{})
+
+	days := make([]string, 0, len(openingHours))
+	for day := range openingHours {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		dayField := field + "." + day
+		v := openingHours[day]
+
+		if _, err := parseWeekday(day); err != nil {
+			verr.add(dayField, "%s", err)
+			continue
+		}
+
+		if _, err := parseOpeningHours(v.(string)); err != nil {
+			verr.add(dayField, "%s", err)
+		}
+	}
+}
+
+func checkTimeZone(verr *ValidationError, field string, value interface{}) {
+	s := value.(string)
+	if s == "" || s == "Local" {
+		verr.add(field, "must be an IANA time zone like Asia/Singapore")
+	} else if _, err := time.LoadLocation(s); err != nil {
+		verr.add(field, "unknown time zone \"%s\"", s)
+	}
+}
+
+// checkLocationTimeZone checks that a location with coordinates has a time zone.
+// Opening hours are read in that time zone, which isn't inferred from the coordinates.
+func checkLocationTimeZone(verr *ValidationError, data map[string]interface{}) {
+	if data["coordinates"] == nil {
+		return
+	}
+
+	if timeZone, _ := data["timeZone"].(string); timeZone == "" {
+		verr.add("timeZone", "is required when the location has coordinates")
+	}
+}
+
+// checkEventSchedule checks that the times of an event and its sessions can be read in its time zone
+// and that they end after they start
+func checkEventSchedule(verr *ValidationError, data map[string]interface{}) {
+	startsAt, _ := data["startsAt"].(string)
+	endsAt, _ := data["endsAt"].(string)
+	sessions, _ := data["sessions"].([]interface{})
+	allDay, _ := data["allDay"].(bool)
+
+	rrule, _ := data["rrule"].(string)
+	exDates, _ := data["exDates"].([]interface{})
+	rDates, _ := data["rDates"].([]interface{})
+
+	if endsAt != "" && startsAt == "" {
+		verr.add("endsAt", "requires startsAt")
+	}
+
+	// Recurring events repeat their own start and end, which sessions don't have
+	recurrence := []struct {
+		field string
+		set   bool
+	}{
+		{"rrule", rrule != ""},
+		{"exDates", len(exDates) > 0},
+		{"rDates", len(rDates) > 0},
+	}
+
+	for _, r := range recurrence {
+		if r.set && startsAt == "" {
+			verr.add(r.field, "requires startsAt")
+		} else if r.set && len(sessions) > 0 {
+			verr.add(r.field, "cannot be combined with sessions")
+		}
+	}
+
+	if startsAt == "" && len(sessions) == 0 {
+		return
+	}
+
+	// Times are still checked against UTC when the time zone is missing or not valid
+	loc := time.UTC
+	if timeZone, _ := data["timeZone"].(string); timeZone == "" {
+		verr.add("timeZone", "is required when the event has a start time or sessions")
+	} else if l, err := time.LoadLocation(timeZone); err == nil {
+		loc = l
+	}
+
+	if startsAt != "" {
+		if _, err := parseEventPeriod(startsAt, endsAt, allDay, loc); err != nil {
+			addEventPeriodError(verr, "", err)
+		}
+	}
+
+	if _, ok := data["rrule"].(string); ok {
+		if _, err := parseRRule(rrule, loc); err != nil {
+			verr.add("rrule", "%s", err)
+		}
+	}
+
+	checkEventDates(verr, "exDates", exDates, allDay, loc)
+	checkEventDates(verr, "rDates", rDates, allDay, loc)
+
+	for i, v := range sessions {
+		session, _ := v.(map[string]interface{})
+		sessionStartsAt, _ := session["startsAt"].(string)
+		sessionEndsAt, _ := session["endsAt"].(string)
+		if sessionStartsAt == "" {
+			continue
+		}
+
+		if _, err := parseEventPeriod(sessionStartsAt, sessionEndsAt, allDay, loc); err != nil {
+			addEventPeriodError(verr, fmt.Sprintf("sessions[%d].", i), err)
+		}
+	}
+}
+
+// checkEventDates checks the extra or excluded starts of a recurring event
+func checkEventDates(verr *ValidationError, field string, dates []interface{}, allDay bool, loc *time.Location) {
+	for i, v := range dates {
+		// Dates that aren't strings have already failed the check of their kind
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		if _, err := parseEventTime(s, allDay, loc); err != nil {
+			verr.add(fmt.Sprintf("%s[%d]", field, i), "%s", err)
+		}
+	}
+}
+
+// addEventPeriodError records an error returned by parseEventPeriod under the field it is about
+func addEventPeriodError(verr *ValidationError, prefix string, err error) {
+	if serr, ok := err.(*scheduleError); ok {
+		verr.add(prefix+serr.Field, "%s", serr.Message)
+	} else {
+		verr.add(prefix+"startsAt", "%s", err)
+	}
+}
+
+func checkLocationStatus(verr *ValidationError, field string, value interface{}) {
+	if s := value.(string); s != LocationStatusOpen && s != LocationStatusClosedIndefinitely {
+		verr.add(field, "must be \"%s\" or \"%s\"", LocationStatusOpen, LocationStatusClosedIndefinitely)
+	}
+}
+
+func checkDate(verr *ValidationError, field string, value interface{}) {
+	if _, err := time.Parse(eventDateLayout, value.(string)); err != nil {
+		verr.add(field, "must be a date like %s", eventDateLayout)
+	}
+}
+
+func checkHours(verr *ValidationError, field string, value interface{}) {
+	if _, err := parseOpeningHours(value.(string)); err != nil {
+		verr.add(field, "%s", err)
+	}
+}
+
+// checkOpeningHoursExceptions checks that each exception is either closed or has hours,
+// and doesn't end before it starts
+func checkOpeningHoursExceptions(verr *ValidationError, field string, value interface{}) {
+	checkObjects(openingHoursExceptionSchema)(verr, field, value)
+
+	for i, v := range value.([]interface{}) {
+		object := v.(map[string]interface{})
+		exceptionField := fmt.Sprintf("%s[%d]", field, i)
+
+		exception := OpeningHoursException{}
+		exception.Date, _ = object["date"].(string)
+		exception.EndDate, _ = object["endDate"].(string)
+		exception.Hours, _ = object["hours"].(string)
+		exception.Closed, _ = object["closed"].(bool)
+
+		if exception.Closed && exception.Hours != "" {
+			verr.add(exceptionField+".hours", "must be left out when closed is true")
+		} else if !exception.Closed && exception.Hours == "" && object["hours"] == nil {
+			verr.add(exceptionField+".hours", "is required unless closed is true")
+		}
+
+		first, errFirst := time.Parse(eventDateLayout, exception.Date)
+		last, errLast := time.Parse(eventDateLayout, exception.EndDate)
+		if errFirst == nil && errLast == nil && last.Before(first) {
+			verr.add(exceptionField+".endDate", "must not be before date")
+		}
+	}
+}
+
+// checkSeasonalOpeningHours checks that each season starts and ends on days that exist
+func checkSeasonalOpeningHours(verr *ValidationError, field string, value interface{}) {
+	checkObjects(seasonalOpeningHoursSchema)(verr, field, value)
+
+	for i, v := range value.([]interface{}) {
+		object := v.(map[string]interface{})
+
+		season := SeasonalOpeningHours{}
+		season.From, _ = object["from"].(string)
+		season.To, _ = object["to"].(string)
+		if season.From == "" || season.To == "" {
+			continue
+		}
+
+		if seasonField, err := checkSeason(season); err != nil {
+			verr.add(fmt.Sprintf("%s[%d].%s", field, i, seasonField), "%s", err)
+		}
+	}
+}
+
+func checkOSMOpeningHours(verr *ValidationError, field string, value interface{}) {
+	if _, _, err := parseOSMOpeningHours(value.(string)); err != nil {
+		verr.add(field, "%s", err)
+	}
+}
+
+func checkPublicHolidayHours(verr *ValidationError, field string, value interface{}) {
+	if s := value.(string); s != publicHolidaysClosed {
+		if _, err := parseOpeningHours(s); err != nil {
+			verr.add(field, "must be \"%s\" or opening hours: %s", publicHolidaysClosed, err)
+		}
+	}
+}
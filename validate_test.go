@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeTestData(t *testing.T, s string) map[string]interface{} {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestValidateItemDataValidLocation(t *testing.T) {
+	data := decodeTestData(t, `{
+		"type": "location",
+		"title": "Coffee Shop",
+		"coordinates": [1.29, 103.85],
+		"websiteURL": "https://example.com",
+		"coverImageURL": "abc123=",
+		"imageURLs": ["data:image/png;base64,iVBORw0KGgo="],
+		"tags": ["coffee"],
+		"openingHours": {"monday": "7.30-15", "Sat": "9-26"},
+		"timeZone": "Asia/Singapore"
+	}`)
+
+	assert.NoError(t, validateItemData(data))
+}
+
+func TestValidateItemDataFieldErrors(t *testing.T) {
+	data := decodeTestData(t, `{
+		"type": "location",
+		"title": "",
+		"coordinates": [91, 200],
+		"websiteURL": "example.com",
+		"coverImageUrl": "abc",
+		"openingHours": {"funday": "9-17", "tuesday": "17-9"},
+		"timeZone": "Asia/Atlantis"
+	}`)
+
+	err := validateItemData(data)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{
+		{"coordinates", "latitude must be between -90 and 90"},
+		{"coordinates", "longitude must be between -180 and 180"},
+		{"coverImageUrl", "unknown field, did you mean \"coverImageURL\"?"},
+		{"openingHours.funday", "\"funday\" is not a day of the week"},
+		{"openingHours.tuesday", "Ending hour cannot be before starting hour"},
+		{"timeZone", "unknown time zone \"Asia/Atlantis\""},
+		{"title", "must not be empty"},
+		{"websiteURL", "must be an absolute http or https URL"},
+		{"coverImageURL", "is required"},
+	}, err.(*ValidationError).Errors)
+}
+
+func TestValidateItemDataLocationTimeZone(t *testing.T) {
+	err := validateItemData(decodeTestData(t, `{
+		"type": "location",
+		"title": "Cathedral",
+		"coverImageURL": "abc123",
+		"coordinates": [42.8806, -8.5446]
+	}`))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{{"timeZone", "is required when the location has coordinates"}}, err.(*ValidationError).Errors)
+}
+
+func TestValidateItemDataUnknownType(t *testing.T) {
+	err := validateItemData(decodeTestData(t, `{"type": "restaurant", "title": "Diner"}`))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{{"type", "unknown item type \"restaurant\""}}, err.(*ValidationError).Errors)
+}
+
+func TestValidateItemDataImageFormats(t *testing.T) {
+	err := validateItemData(decodeTestData(t, `{
+		"type": "event",
+		"title": "Fair",
+		"coverImageURL": "data:image/bmp;base64,Qk0=",
+		"imageURLs": ["data:image/svg+xml;base64,PHN2Zz4=", "data:text/plain;base64,aGk="]
+	}`))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{
+		{"coverImageURL", "must be a JPEG, PNG, GIF, WebP or SVG image"},
+		{"imageURLs[1]", "must be a base64 encoded image data URI"},
+	}, err.(*ValidationError).Errors)
+}
+
+func TestValidateItemDataEventSchedule(t *testing.T) {
+	assert.NoError(t, validateItemData(decodeTestData(t, `{
+		"type": "event",
+		"title": "Festival",
+		"coverImageURL": "abc123",
+		"timeZone": "Asia/Singapore",
+		"allDay": true,
+		"sessions": [{"title": "Day 1", "startsAt": "2026-05-01"}, {"startsAt": "2026-05-02", "endsAt": "2026-05-03"}]
+	}`)))
+
+	err := validateItemData(decodeTestData(t, `{
+		"type": "event",
+		"title": "Talk",
+		"coverImageURL": "abc123",
+		"startsAt": "2026-05-01T19:00",
+		"endsAt": "2026-05-01T18:00",
+		"timeZone": "Mars/Olympus_Mons",
+		"sessions": [{"startsAt": "tomorrow", "room": "A"}, {"endsAt": "2026-05-01T20:00"}]
+	}`))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{
+		{"sessions[0].room", "unknown field"},
+		{"sessions[1].startsAt", "is required"},
+		{"timeZone", "unknown time zone \"Mars/Olympus_Mons\""},
+		{"endsAt", "must be after startsAt"},
+		{"sessions[0].startsAt", "must be a local time like 2006-01-02T15:04"},
+	}, err.(*ValidationError).Errors)
+
+	// Dates of the wrong kind are only reported once, by the check of their kind
+	err = validateItemData(decodeTestData(t, `{
+		"type": "event",
+		"title": "Class",
+		"coverImageURL": "abc123",
+		"timeZone": "Asia/Singapore",
+		"startsAt": "2026-05-01T19:00",
+		"rrule": "FREQ=WEEKLY",
+		"exDates": [1, "2026-05-08T19:00"],
+		"rDates": [true]
+	}`))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{
+		{"exDates", "must be an array of strings"},
+		{"rDates", "must be an array of strings"},
+	}, err.(*ValidationError).Errors)
+}
+
+func TestValidateItemDataOpeningHoursExceptions(t *testing.T) {
+	assert.NoError(t, validateItemData(decodeTestData(t, `{
+		"type": "location",
+		"title": "Museum",
+		"coverImageURL": "abc123",
+		"status": "open",
+		"openingHoursExceptions": [
+			{"date": "2026-12-25", "closed": true, "note": "Christmas"},
+			{"date": "2026-12-31", "endDate": "2027-01-01", "hours": "10-14"}
+		],
+		"seasonalOpeningHours": [{"name": "Summer", "from": "06-01", "to": "08-31", "openingHours": {"mon": "9-21"}}]
+	}`)))
+
+	err := validateItemData(decodeTestData(t, `{
+		"type": "location",
+		"title": "Museum",
+		"coverImageURL": "abc123",
+		"status": "gone",
+		"openingHoursExceptions": [
+			{"date": "25 Dec", "closed": true},
+			{"date": "2026-12-31", "endDate": "2026-12-30", "hours": "10-14", "closed": true},
+			{"date": "2026-01-01"}
+		],
+		"seasonalOpeningHours": [
+			{"from": "2026-06-01", "to": "08-31", "openingHours": {"mon": "9-21"}},
+			{"from": "02-30", "to": "03-31"}
+		]
+	}`))
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, []FieldError{
+		{"openingHoursExceptions[0].date", "must be a date like 2006-01-02"},
+		{"openingHoursExceptions[1].hours", "must be left out when closed is true"},
+		{"openingHoursExceptions[1].endDate", "must not be before date"},
+		{"openingHoursExceptions[2].hours", "is required unless closed is true"},
+		{"seasonalOpeningHours[1].openingHours", "is required"},
+		{"seasonalOpeningHours[0].to", "must be in the same format as from"},
+		{"seasonalOpeningHours[1].from", "must be a date like 2006-01-02, or a day like 01-02 that repeats every year"},
+		{"status", "must be \"open\" or \"closed-indefinitely\""},
+	}, err.(*ValidationError).Errors)
+}